@@ -2,6 +2,7 @@ package backup
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -18,55 +19,61 @@ var Cmd = &bonzai.Cmd{
 	Short: "backup existing dotfiles",
 	Cmds:  []*bonzai.Cmd{help.Cmd},
 	Do: func(caller *bonzai.Cmd, args ...string) error {
-		// Load configuration
-		cfg, err := config.LoadConfig("arara.yaml")
-		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
-		}
+		return Run(os.Stdout, ".")
+	},
+}
 
-		// Create backup directory with timestamp
-		backupDir := filepath.Join(os.Getenv("HOME"),
-			fmt.Sprintf("dotbk-%d", time.Now().Unix()))
+// Run backs up the backup_dirs of the arara.yaml in dotfiles, writing
+// progress to w
+func Run(w io.Writer, dotfiles string) error {
+	// Load configuration
+	cfg, err := config.LoadMergedConfig(filepath.Join(dotfiles, "arara.yaml"))
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
 
-		if err := os.MkdirAll(backupDir, 0755); err != nil {
-			return fmt.Errorf("failed to create backup dir: %w", err)
-		}
+	// Create backup directory with timestamp
+	backupDir := filepath.Join(os.Getenv("HOME"),
+		fmt.Sprintf("dotbk-%d", time.Now().Unix()))
+
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup dir: %w", err)
+	}
 
-		// Backup directories specified in config
-		for _, dir := range cfg.Setup.BackupDirs {
-			// Expand environment variables in path
-			expandedDir := os.ExpandEnv(dir)
+	// Backup directories specified in config
+	for _, dir := range cfg.Setup.BackupDirs {
+		// Expand environment variables in path
+		expandedDir := os.ExpandEnv(dir)
 
-			// Get the base name of the directory
-			baseName := filepath.Base(expandedDir)
+		// Get the base name of the directory
+		baseName := filepath.Base(expandedDir)
 
-			// Create destination path
-			dst := filepath.Join(backupDir, baseName)
+		// Create destination path
+		dst := filepath.Join(backupDir, baseName)
 
-			// Skip if source doesn't exist
-			if _, err := os.Stat(expandedDir); os.IsNotExist(err) {
-				fmt.Printf("Skipping non-existent directory: %s\n", expandedDir)
-				continue
-			}
+		// Skip if source doesn't exist
+		if _, err := os.Stat(expandedDir); os.IsNotExist(err) {
+			fmt.Fprintf(w, "Skipping non-existent directory: %s\n", expandedDir)
+			continue
+		}
 
-			// Try renaming first (faster if on same filesystem)
-			err := os.Rename(expandedDir, dst)
-			if err != nil {
-				// If rename fails, try copying
-				if err := futil.Replace(dst, expandedDir); err != nil {
-					return fmt.Errorf("failed to backup %s: %w", expandedDir, err)
-				}
-				// After successful copy, remove the original
-				if err := os.RemoveAll(expandedDir); err != nil {
-					return fmt.Errorf("failed to remove original after backup %s: %w", expandedDir, err)
-				}
+		// Try renaming first (faster if on same filesystem)
+		err := os.Rename(expandedDir, dst)
+		if err != nil {
+			// If rename fails, try copying
+			if err := futil.Replace(dst, expandedDir); err != nil {
+				return fmt.Errorf("failed to backup %s: %w", expandedDir, err)
+			}
+			// After successful copy, remove the original
+			if err := os.RemoveAll(expandedDir); err != nil {
+				return fmt.Errorf("failed to remove original after backup %s: %w", expandedDir, err)
 			}
-			fmt.Printf("Backed up %s to %s\n", expandedDir, dst)
 		}
+		fmt.Fprintf(w, "Backed up %s to %s\n", expandedDir, dst)
+	}
 
-		fmt.Printf("Backup created at: %s\n", backupDir)
-		return nil
-	},
+	fmt.Fprintf(w, "Backup created at: %s\n", backupDir)
+	return nil
 }
 
 // copyDir recursively copies a directory tree
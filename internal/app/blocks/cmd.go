@@ -0,0 +1,173 @@
+package blocks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rwxrob/bonzai"
+	"github.com/rwxrob/bonzai/cmds/help"
+
+	"github.com/BuddhiLW/arara/internal/pkg/blocks"
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+var Cmd = &bonzai.Cmd{
+	Name:  "blocks",
+	Alias: "bl",
+	Short: "write managed blocks into files",
+	Usage: "blocks [--remove] [name...]",
+	Long: `
+Write the blocks declared in the active namespace's arara.yaml into their
+files. A block is content arara owns inside a file it doesn't, between
+marker comments, so the rest of the file is left alone:
+
+  blocks:
+    - name: work-tools
+      file: $HOME/.bashrc
+      content: |
+        export PATH="$HOME/work/tools/bin:$PATH"
+    - name: plugins
+      file: $HOME/.vimrc
+      content: source $DOTFILES/vim/plugins.vim
+    - name: old-aliases
+      file: $HOME/.profile
+      absent: true
+
+writes into ~/.bashrc:
+
+  # >>> arara work-tools >>>
+  export PATH="$HOME/work/tools/bin:$PATH"
+  # <<< arara work-tools <<<
+
+Running it again updates the block in place, so edits between the markers
+are overwritten. Files are created if missing. $DOTFILES and environment
+variables are expanded in file, not in content.
+
+The markers use the comment syntax of the file, guessed from its name (",
+--, //, <!-- --> and others, # by default). Set comment to override it,
+with the end after a space for block comments, like comment: "/* */".
+
+Blocks with absent: true are removed, and --remove removes the named
+blocks, or all of them. Blocks of the namespace extended with extends: are
+included.
+
+The name local-bin is reserved for the PATH block 'arara create setup-path'
+keeps in ~/.bashrc, and blocks declaring it are refused.
+
+Example:
+  arara setup blocks
+  arara setup blocks --remove work-tools
+`,
+	Cmds: []*bonzai.Cmd{help.Cmd},
+	Do: func(caller *bonzai.Cmd, args ...string) error {
+		var remove bool
+		var names []string
+		for _, arg := range args {
+			if arg == "--remove" {
+				remove = true
+				continue
+			}
+			names = append(names, arg)
+		}
+
+		dotfilesPath, err := config.GetDotfilesPath()
+		if err != nil {
+			return fmt.Errorf("failed to get dotfiles path: %w", err)
+		}
+		if dotfilesPath == "" {
+			return fmt.Errorf("no active dotfiles repository found")
+		}
+		cfg, err := config.LoadMergedConfig(filepath.Join(dotfilesPath, "arara.yaml"))
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		selected, err := selectBlocks(cfg.Blocks, names)
+		if err != nil {
+			return err
+		}
+		if len(selected) == 0 {
+			fmt.Println("No blocks declared in arara.yaml")
+			return nil
+		}
+
+		var failed []string
+		for _, b := range selected {
+			if err := applyBlock(b, dotfilesPath, remove || b.Absent); err != nil {
+				fmt.Fprintf(os.Stderr, "✗ %s: %v\n", b.Name, err)
+				failed = append(failed, b.Name)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to update blocks: %s", strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+// selectBlocks returns the blocks called names, or all without names
+func selectBlocks(all []config.Block, names []string) ([]config.Block, error) {
+	if len(names) == 0 {
+		return all, nil
+	}
+	var selected []config.Block
+	for _, name := range names {
+		found := false
+		for _, b := range all {
+			if b.Name == name {
+				selected = append(selected, b)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("block not found: %s", name)
+		}
+	}
+	return selected, nil
+}
+
+// applyBlock writes or removes b, $DOTFILES in its file referring to
+// dotfiles
+func applyBlock(b config.Block, dotfiles string, remove bool) error {
+	if b.Name == "" || strings.ContainsAny(b.Name, "\n") {
+		return fmt.Errorf("invalid block name %q", b.Name)
+	}
+	if b.Name == blocks.LocalBin {
+		return fmt.Errorf("block name %q is reserved for 'arara create setup-path'", b.Name)
+	}
+	if b.File == "" {
+		return fmt.Errorf("no file set")
+	}
+	path := config.Link{Target: b.File}.Expand(dotfiles).Target
+
+	syn := blocks.SyntaxFor(path)
+	if b.Comment != "" {
+		syn = blocks.ParseSyntax(b.Comment)
+	}
+
+	if remove {
+		changed, err := blocks.RemoveFile(path, b.Name, syn)
+		switch {
+		case err != nil:
+			return err
+		case changed:
+			fmt.Printf("✓ %s: removed from %s\n", b.Name, path)
+		default:
+			fmt.Printf("  %s: not in %s\n", b.Name, path)
+		}
+		return nil
+	}
+
+	changed, err := blocks.SetFile(path, b.Name, b.Content, syn)
+	switch {
+	case err != nil:
+		return err
+	case changed:
+		fmt.Printf("✓ %s: updated %s\n", b.Name, path)
+	default:
+		fmt.Printf("  %s: %s is up to date\n", b.Name, path)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package blocks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+func TestBlocksCmd(t *testing.T) {
+	tmpDir := t.TempDir()
+	home := filepath.Join(tmpDir, "home")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+	t.Setenv("HOME", home)
+	t.Setenv("ARARA_ACTIVE_NAMESPACE", "work")
+
+	dotfiles := filepath.Join(tmpDir, "work")
+	if err := os.MkdirAll(dotfiles, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.AddNamespace("work", dotfiles, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfig := func(yaml string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dotfiles, "arara.yaml"), []byte(yaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	bashrc := filepath.Join(home, ".bashrc")
+	if err := os.MkdirAll(home, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bashrc, []byte("alias ll='ls -l'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfig(`
+blocks:
+  - name: aliases
+    file: $HOME/.bashrc
+    content: source $DOTFILES/aliases.sh
+  - name: theme
+    file: $HOME/.config/app/style.css
+    content: "@import url(theme.css);"
+`)
+	if err := Cmd.Do(Cmd); err != nil {
+		t.Fatalf("blocks failed: %v", err)
+	}
+	want := "alias ll='ls -l'\n\n# >>> arara aliases >>>\nsource $DOTFILES/aliases.sh\n# <<< arara aliases <<<\n"
+	if got := read(bashrc); got != want {
+		t.Errorf(".bashrc:\n%q\nwant:\n%q", got, want)
+	}
+	if got := read(filepath.Join(home, ".config", "app", "style.css")); !strings.HasPrefix(got, "/* >>> arara theme >>> */\n") {
+		t.Errorf("style.css does not use CSS comments:\n%s", got)
+	}
+
+	// Applying again leaves the files alone
+	if err := Cmd.Do(Cmd); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(bashrc); got != want {
+		t.Errorf("rerun changed .bashrc:\n%q", got)
+	}
+
+	if err := Cmd.Do(Cmd, "--remove", "theme"); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(filepath.Join(home, ".config", "app", "style.css")); got != "" {
+		t.Errorf("theme not removed:\n%q", got)
+	}
+	if err := Cmd.Do(Cmd, "missing"); err == nil {
+		t.Error("expected an error for an undeclared block")
+	}
+
+	// setup-path's block is not for arara.yaml to declare
+	writeConfig(`
+blocks:
+  - name: local-bin
+    file: $HOME/.bashrc
+    content: export PATH="$HOME/bin:$PATH"
+`)
+	if err := Cmd.Do(Cmd); err == nil {
+		t.Error("expected an error for the reserved local-bin block")
+	}
+	if got := read(bashrc); got != want {
+		t.Errorf("reserved block written:\n%q", got)
+	}
+
+	writeConfig(`
+blocks:
+  - name: aliases
+    file: $HOME/.bashrc
+    absent: true
+`)
+	if err := Cmd.Do(Cmd); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(bashrc); got != "alias ll='ls -l'\n" {
+		t.Errorf("absent block not removed:\n%q", got)
+	}
+}
@@ -2,12 +2,20 @@ package build
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 
+	"github.com/BuddhiLW/arara/internal/app/backup"
+	"github.com/BuddhiLW/arara/internal/app/compat"
+	"github.com/BuddhiLW/arara/internal/app/link"
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/runlog"
+	"github.com/BuddhiLW/arara/internal/pkg/vars"
 	"github.com/rwxrob/bonzai"
 	"github.com/rwxrob/bonzai/cmds/help"
+	bonzaiVars "github.com/rwxrob/bonzai/vars"
 )
 
 // Cmd represents the build command
@@ -15,7 +23,53 @@ var Cmd = &bonzai.Cmd{
 	Name:  "build",
 	Alias: "b",
 	Short: "execute or list build steps from arara.yaml",
-	Cmds:  []*bonzai.Cmd{help.Cmd, listCmd, installCmd},
+	Long: `
+Set up the active namespace: back up the directories being replaced,
+create the symlinks, set up the xmonad window manager, then run the
+steps of the build section of its arara.yaml in order, after those of
+the namespace it extends:
+
+  build:
+    steps:
+      - name: fonts
+        description: Refresh the font cache
+        command: fc-cache -f
+      - name: services
+        commands:
+          - systemctl enable --now docker
+        privileged: true
+        compat:
+          os: linux
+
+Commands run through sh from the dotfiles directory, with DOTFILES,
+ARARA_DOTFILES, ARARA_NAMESPACE and the env of arara.yaml set. Steps
+inherited through extends run from the base repository, and DOTFILES
+points there. Steps that are not compatible with this system are
+skipped. Steps with privileged: true run as root through the configured
+escalation tool instead of embedding sudo; credentials are asked for
+once, before the first step.
+
+The window manager stage clones xmonad and xmonad-contrib into
+~/.config/xmonad and installs Haskell Stack.
+`,
+	Cmds: []*bonzai.Cmd{help.Cmd, listCmd, installCmd},
+}
+
+// loadBuild returns the dotfiles path and config of the active namespace,
+// merged with the namespaces it extends
+func loadBuild() (string, *config.DotfilesConfig, error) {
+	dotfiles, err := config.GetDotfilesPath()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get dotfiles path: %w", err)
+	}
+	if dotfiles == "" {
+		return "", nil, fmt.Errorf("no active dotfiles repository found")
+	}
+	cfg, err := config.LoadMergedConfig(filepath.Join(dotfiles, "arara.yaml"))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return dotfiles, cfg, nil
 }
 
 // listCmd lists all build steps from arara.yaml
@@ -25,11 +79,28 @@ var listCmd = &bonzai.Cmd{
 	Short: "list available build steps",
 	Cmds:  []*bonzai.Cmd{help.Cmd},
 	Do: func(caller *bonzai.Cmd, args ...string) error {
-		// TODO: Read from arara.yaml and display build steps
+		_, cfg, err := loadBuild()
+		if err != nil {
+			return err
+		}
+
 		fmt.Println("Available build steps:")
 		fmt.Println("  - backup: Backup existing dotfiles")
 		fmt.Println("  - link: Create symlinks")
 		fmt.Println("  - xmonad: Setup window manager")
+		for _, step := range cfg.Build.Steps {
+			line := "  - " + step.Name
+			if step.Description != "" {
+				line += ": " + step.Description
+			}
+			if step.Privileged {
+				line += " (privileged)"
+			}
+			if failures := compat.Failures(compat.FromConfig(step.Compat)); len(failures) > 0 {
+				line += fmt.Sprintf(" [skipped: %s]", failures[0])
+			}
+			fmt.Println(line)
+		}
 		return nil
 	},
 }
@@ -41,70 +112,125 @@ var installCmd = &bonzai.Cmd{
 	Short: "execute fresh dotfiles installation",
 	Cmds:  []*bonzai.Cmd{help.Cmd},
 	Do: func(caller *bonzai.Cmd, args ...string) error {
-		fmt.Println("Executing build steps...")
-		
-		// Execute backup step
-		fmt.Println("1. Backing up existing dotfiles...")
-		backupCmd := exec.Command("arara", "setup", "backup")
-		backupCmd.Stdout = os.Stdout
-		backupCmd.Stderr = os.Stderr
-		if err := backupCmd.Run(); err != nil {
-			return fmt.Errorf("failed to backup existing dotfiles: %w", err)
-		}
-		
-		// Execute link step
-		fmt.Println("2. Creating symlinks...")
-		linkCmd := exec.Command("arara", "setup", "link")
-		linkCmd.Stdout = os.Stdout
-		linkCmd.Stderr = os.Stderr
-		if err := linkCmd.Run(); err != nil {
-			return fmt.Errorf("failed to create symlinks: %w", err)
-		}
-		
-		// Execute xmonad setup step
-		fmt.Println("3. Setting up window manager...")
-		homeDir, err := os.UserHomeDir()
+		dotfiles, cfg, err := loadBuild()
 		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
-		}
-		
-		xmonadConfigDir := filepath.Join(homeDir, ".config", "xmonad")
-		if err := os.Chdir(xmonadConfigDir); err != nil {
-			return fmt.Errorf("failed to change to xmonad config directory: %w", err)
+			return err
 		}
-		
-		// Remove existing xmonad repos
-		if err := os.RemoveAll("xmonad"); err != nil {
-			return fmt.Errorf("failed to remove existing xmonad repo: %w", err)
+
+		// Ask for credentials once and keep them cached for the whole
+		// build, so privileged steps don't prompt midway
+		if needsPrivilege(cfg.Build.Steps) {
+			session, err := privilegeSession()
+			if err != nil {
+				return err
+			}
+			defer session.End()
 		}
-		if err := os.RemoveAll("xmonad-contrib"); err != nil {
-			return fmt.Errorf("failed to remove existing xmonad-contrib repo: %w", err)
+
+		// Record the build and its output in the run log; a failing log
+		// must not block the build
+		var stdout, stderr io.Writer = os.Stdout, os.Stderr
+		ns := bonzaiVars.Fetch(vars.ActiveNamespaceEnv, vars.ActiveNamespaceVar, "")
+		run, err := runlog.Begin("build", "install", "", ns)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: not recording run: %v\n", err)
+		} else {
+			stdout = io.MultiWriter(os.Stdout, run.Log())
+			stderr = io.MultiWriter(os.Stderr, run.Log())
 		}
-		
-		// Clone xmonad repositories
-		xmonadCmd := exec.Command("git", "clone", "https://github.com/xmonad/xmonad")
-		xmonadCmd.Stdout = os.Stdout
-		xmonadCmd.Stderr = os.Stderr
-		if err := xmonadCmd.Run(); err != nil {
-			return fmt.Errorf("failed to clone xmonad repository: %w", err)
+
+		err = runBuild(cfg, dotfiles, ns, stdout, stderr)
+
+		if run != nil {
+			if ferr := run.Finish(err); ferr != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to record run: %v\n", ferr)
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "See 'arara logs %s' for the full output\n", run.ID)
+			}
 		}
-		
-		xmonadContribCmd := exec.Command("git", "clone", "https://github.com/xmonad/xmonad-contrib")
-		xmonadContribCmd.Stdout = os.Stdout
-		xmonadContribCmd.Stderr = os.Stderr
-		if err := xmonadContribCmd.Run(); err != nil {
-			return fmt.Errorf("failed to clone xmonad-contrib repository: %w", err)
+
+		return err
+	},
+}
+
+// runBuild executes the build steps writing command output to stdout and stderr
+func runBuild(cfg *config.DotfilesConfig, dotfiles, ns string, stdout, stderr io.Writer) error {
+	fmt.Fprintln(stdout, "Executing build steps...")
+
+	// Execute backup step
+	fmt.Fprintln(stdout, "1. Backing up existing dotfiles...")
+	if err := backup.Run(stdout, dotfiles); err != nil {
+		return fmt.Errorf("failed to backup existing dotfiles: %w", err)
+	}
+
+	// Execute link step
+	fmt.Fprintln(stdout, "2. Creating symlinks...")
+	if err := link.Run(stdout, dotfiles); err != nil {
+		return fmt.Errorf("failed to create symlinks: %w", err)
+	}
+
+	// Execute xmonad setup step
+	fmt.Fprintln(stdout, "3. Setting up window manager...")
+	if err := setupXmonad(stdout, stderr); err != nil {
+		return err
+	}
+
+	// Execute the steps declared in arara.yaml
+	if len(cfg.Build.Steps) > 0 {
+		fmt.Fprintln(stdout, "4. Running configured steps...")
+		if err := runSteps(cfg, dotfiles, ns, stdout, stderr); err != nil {
+			return err
 		}
-		
-		// Install Haskell Stack
-		stackCmd := exec.Command("bash", "-c", "curl -sSL https://get.haskellstack.org/ | sh -s - -f")
-		stackCmd.Stdout = os.Stdout
-		stackCmd.Stderr = os.Stderr
-		if err := stackCmd.Run(); err != nil {
-			return fmt.Errorf("failed to install Haskell Stack: %w", err)
+	}
+
+	fmt.Fprintln(stdout, "Build completed successfully!")
+	return nil
+}
+
+// execCommand creates the commands of the window manager stage; replaced
+// in tests
+var execCommand = exec.Command
+
+// setupXmonad clones xmonad and xmonad-contrib afresh into
+// ~/.config/xmonad and installs Haskell Stack
+func setupXmonad(stdout, stderr io.Writer) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	xmonadConfigDir := filepath.Join(homeDir, ".config", "xmonad")
+	if info, err := os.Stat(xmonadConfigDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("failed to change to xmonad config directory: %s is not a directory", xmonadConfigDir)
+	}
+
+	// Remove existing xmonad repos
+	if err := os.RemoveAll(filepath.Join(xmonadConfigDir, "xmonad")); err != nil {
+		return fmt.Errorf("failed to remove existing xmonad repo: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Join(xmonadConfigDir, "xmonad-contrib")); err != nil {
+		return fmt.Errorf("failed to remove existing xmonad-contrib repo: %w", err)
+	}
+
+	run := func(what string, name string, args ...string) error {
+		cmd := execCommand(name, args...)
+		cmd.Dir = xmonadConfigDir
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to %s: %w", what, err)
 		}
-		
-		fmt.Println("Build completed successfully!")
 		return nil
-	},
-}
\ No newline at end of file
+	}
+
+	// Clone xmonad repositories
+	if err := run("clone xmonad repository", "git", "clone", "https://github.com/xmonad/xmonad"); err != nil {
+		return err
+	}
+	if err := run("clone xmonad-contrib repository", "git", "clone", "https://github.com/xmonad/xmonad-contrib"); err != nil {
+		return err
+	}
+
+	// Install Haskell Stack
+	return run("install Haskell Stack", "bash", "-c", "curl -sSL https://get.haskellstack.org/ | sh -s - -f")
+}
@@ -4,11 +4,60 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/privilege"
 )
 
+// setupNamespace registers a test namespace whose arara.yaml has steps
+func setupNamespace(t *testing.T) string {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(tmpDir, "state"))
+	t.Setenv("ARARA_ACTIVE_NAMESPACE", "test")
+	t.Setenv("TEST_MODE", "1")
+
+	dotfiles := filepath.Join(tmpDir, "dotfiles")
+	if err := os.MkdirAll(dotfiles, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dotfiles, "arara.yaml"), []byte(`
+env:
+  GREETING: hello
+build:
+  steps:
+    - name: greet
+      description: Write a greeting
+      command: echo "$GREETING $ARARA_NAMESPACE" > greeting
+    - name: elsewhere
+      command: touch elsewhere
+      compat:
+        os: nonexistent-os
+    - name: as-root
+      privileged: true
+      commands:
+        - echo "$GREETING" > root
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.AddNamespace("test", dotfiles, ""); err != nil {
+		t.Fatal(err)
+	}
+	return dotfiles
+}
+
 func TestListCmd(t *testing.T) {
+	setupNamespace(t)
+
 	// Capture stdout to verify output
 	oldStdout := os.Stdout
 	r, w, _ := os.Pipe()
@@ -16,13 +65,13 @@ func TestListCmd(t *testing.T) {
 	
 	// Run the list command
 	err := listCmd.Do(listCmd)
-	if err != nil {
-		t.Fatalf("Failed to execute list command: %v", err)
-	}
 	
 	// Reset stdout
 	w.Close()
 	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("Failed to execute list command: %v", err)
+	}
 	
 	// Read captured output
 	var buf bytes.Buffer
@@ -34,6 +83,9 @@ func TestListCmd(t *testing.T) {
 		"backup",
 		"link",
 		"xmonad",
+		"greet: Write a greeting",
+		"elsewhere [skipped:",
+		"as-root (privileged)",
 	}
 	
 	for _, step := range expectedSteps {
@@ -43,6 +95,150 @@ func TestListCmd(t *testing.T) {
 	}
 }
 
+func TestRunSteps(t *testing.T) {
+	dotfiles := setupNamespace(t)
+	_, cfg, err := loadBuild()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A fake escalation tool that records its arguments and runs the command
+	doas := filepath.Join(t.TempDir(), "doas")
+	script := "#!/bin/sh\necho \"$*\" >> \"" + doas + ".log\"\nexec \"$@\"\n"
+	if err := os.WriteFile(doas, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	origSession := privilegeSession
+	privilegeSession = func() (*privilege.Session, error) {
+		return &privilege.Session{Tool: doas}, nil
+	}
+	defer func() { privilegeSession = origSession }()
+
+	if !needsPrivilege(cfg.Build.Steps) {
+		t.Error("as-root should need privilege")
+	}
+	var out bytes.Buffer
+	if err := runSteps(cfg, dotfiles, "test", &out, &out); err != nil {
+		t.Fatalf("runSteps() error = %v\n%s", err, out.String())
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dotfiles, name))
+		if err != nil {
+			t.Errorf("step did not write %s: %v", name, err)
+		}
+		return strings.TrimSpace(string(data))
+	}
+	if got := read("greeting"); got != "hello test" {
+		t.Errorf("greeting = %q, want %q", got, "hello test")
+	}
+	if got := read("root"); got != "hello" {
+		t.Errorf("root = %q, want %q", got, "hello")
+	}
+	if _, err := os.Stat(filepath.Join(dotfiles, "elsewhere")); err == nil {
+		t.Error("incompatible step should be skipped")
+	}
+
+	// Only the privileged step went through the escalation tool
+	log, err := os.ReadFile(doas + ".log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "env ") || !strings.Contains(lines[0], "GREETING=hello") ||
+		!strings.HasSuffix(lines[0], `sh -c echo "$GREETING" > root`) {
+		t.Errorf("escalation tool ran %q", lines)
+	}
+}
+
+func TestInstallCmd(t *testing.T) {
+	team := setupNamespace(t)
+	home := filepath.Join(t.TempDir(), "home")
+	for _, dir := range []string{".vim", ".config/xmonad/xmonad"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("HOME", home)
+
+	// A personal namespace layered over the test one, with its own link
+	personal := filepath.Join(t.TempDir(), "personal")
+	if err := os.MkdirAll(personal, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(personal, "arara.yaml"), []byte(`
+extends: test
+setup:
+  backup_dirs: ["$HOME/.vim"]
+  config_links:
+    - source: $DOTFILES/bashrc
+      target: $HOME/.bashrc
+build:
+  steps:
+    - name: as-root
+      command: pwd > here
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.AddNamespace("personal", personal, ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ARARA_ACTIVE_NAMESPACE", "personal")
+
+	// The local as-root step replaces the privileged one of the base
+	privilegeSession = func() (*privilege.Session, error) {
+		t.Fatal("no step should need privilege")
+		return nil, nil
+	}
+	defer func() { privilegeSession = privilege.Shared }()
+
+	// Record the window manager commands instead of downloading anything
+	var ran []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		ran = append(ran, name+" "+strings.Join(args, " "))
+		return exec.Command("true")
+	}
+	defer func() { execCommand = exec.Command }()
+
+	oldStdout := os.Stdout
+	devNull, _ := os.Open(os.DevNull)
+	os.Stdout = devNull
+	err = installCmd.Do(installCmd)
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("installCmd.Do() error = %v", err)
+	}
+
+	// Backup and link ran on the active namespace, not the working directory
+	if _, err := os.Stat(filepath.Join(home, ".vim")); !os.IsNotExist(err) {
+		t.Errorf("~/.vim should have been backed up, stat error = %v", err)
+	}
+	if target, _ := os.Readlink(filepath.Join(home, ".bashrc")); target != filepath.Join(personal, "bashrc") {
+		t.Errorf(".bashrc links to %q, want %s", target, filepath.Join(personal, "bashrc"))
+	}
+
+	// The window manager stage cloned xmonad afresh and installed Stack
+	if _, err := os.Stat(filepath.Join(home, ".config", "xmonad", "xmonad")); !os.IsNotExist(err) {
+		t.Errorf("existing xmonad clone should have been removed, stat error = %v", err)
+	}
+	if len(ran) != 3 || ran[0] != "git clone https://github.com/xmonad/xmonad" ||
+		ran[1] != "git clone https://github.com/xmonad/xmonad-contrib" || !strings.HasPrefix(ran[2], "bash -c curl") {
+		t.Errorf("window manager stage ran %q", ran)
+	}
+
+	// The inherited step ran in the base repository, the local one locally
+	if data, err := os.ReadFile(filepath.Join(team, "greeting")); err != nil || strings.TrimSpace(string(data)) != "hello personal" {
+		t.Errorf("inherited greet step wrote %q (%v)", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(personal, "here")); err != nil || strings.TrimSpace(string(data)) != personal {
+		t.Errorf("local step ran in %q (%v), want %s", data, err, personal)
+	}
+}
+
 // Mock function to use for testing the install command without executing external commands
 // We're not testing this now because it would require significant mocking of external commands
 func mockExecCommand(command string, args ...string) *mockCmd {
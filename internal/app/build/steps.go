@@ -0,0 +1,134 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/BuddhiLW/arara/internal/app/compat"
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/privilege"
+)
+
+// privilegeSession returns the run's privilege escalation session, so
+// credentials are validated once for all steps; replaced in tests
+var privilegeSession = privilege.Shared
+
+// stepCommands returns the shell commands of step, command first
+func stepCommands(step config.Step) []string {
+	var cmds []string
+	if step.Command != "" {
+		cmds = append(cmds, step.Command)
+	}
+	return append(cmds, step.Commands...)
+}
+
+// needsPrivilege reports whether any of steps runs as root
+func needsPrivilege(steps []config.Step) bool {
+	for _, step := range steps {
+		if step.Privileged && len(stepCommands(step)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// stepEnv returns the environment of build steps: the process env with
+// DOTFILES and the ARARA_* vars set and the namespace env expanded over it
+func stepEnv(cfg *config.DotfilesConfig, dotfiles, ns string) []string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	env["DOTFILES"] = dotfiles
+	env["ARARA_DOTFILES"] = dotfiles
+	env["ARARA_NAMESPACE"] = ns
+
+	keys := make([]string, 0, len(cfg.Env))
+	for k := range cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env[k] = os.Expand(cfg.Env[k], func(k string) string { return env[k] })
+	}
+
+	keys = keys[:0]
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, k+"="+env[k])
+	}
+	return out
+}
+
+// addedEnv returns the entries of env that differ from the process env
+func addedEnv(env []string) []string {
+	current := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		current[kv] = true
+	}
+	var added []string
+	for _, kv := range env {
+		if !current[kv] {
+			added = append(added, kv)
+		}
+	}
+	return added
+}
+
+// runSteps runs the build steps of cfg in order from the dotfiles
+// directory, or the base one for inherited steps, each command through
+// sh. Steps incompatible with this system are skipped and privileged
+// steps run through the escalation tool.
+func runSteps(cfg *config.DotfilesConfig, dotfiles, ns string, stdout, stderr io.Writer) error {
+	for i, step := range cfg.Build.Steps {
+		if failures := compat.Failures(compat.FromConfig(step.Compat)); len(failures) > 0 {
+			fmt.Fprintf(stdout, "Skipping step %s: %s\n", step.Name, failures[0])
+			continue
+		}
+
+		label := step.Name
+		if step.Description != "" {
+			label += ": " + step.Description
+		}
+		fmt.Fprintf(stdout, "Step %d/%d %s\n", i+1, len(cfg.Build.Steps), label)
+
+		dir := dotfiles
+		if step.Dir != "" {
+			dir = step.Dir
+		}
+		env := stepEnv(cfg, dir, ns)
+
+		for _, command := range stepCommands(step) {
+			args := []string{"sh", "-c", command}
+			if step.Privileged {
+				s, err := privilegeSession()
+				if err != nil {
+					return err
+				}
+				// Escalation resets the environment; pass on what arara adds
+				args = s.CommandEnv(addedEnv(env), args...)
+			}
+
+			cmd := exec.Command(args[0], args[1:]...)
+			cmd.Dir = dir
+			cmd.Env = env
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = stdout
+			cmd.Stderr = stderr
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("step %s failed: %w", step.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -14,11 +14,14 @@ import (
 	"github.com/BuddhiLW/arara/internal/app/compat"
 	"github.com/BuddhiLW/arara/internal/app/create"
 	"github.com/BuddhiLW/arara/internal/app/deps"
+	"github.com/BuddhiLW/arara/internal/app/history"
 	"github.com/BuddhiLW/arara/internal/app/install"
 	"github.com/BuddhiLW/arara/internal/app/link"
 	"github.com/BuddhiLW/arara/internal/app/list"
+	"github.com/BuddhiLW/arara/internal/app/logs"
 	"github.com/BuddhiLW/arara/internal/app/namespace"
 	"github.com/BuddhiLW/arara/internal/app/setup"
+	"github.com/BuddhiLW/arara/internal/app/shellinit"
 	"github.com/BuddhiLW/arara/internal/app/sync"
 	"github.com/BuddhiLW/arara/internal/pkg/config"
 )
@@ -27,10 +30,12 @@ const (
 	// Environment variables
 	ActiveNamespaceEnv = "ARARA_ACTIVE_NAMESPACE"
 	DotfilesPathEnv    = "ARARA_DOTFILES_PATH"
+	PrivilegeEnv       = "ARARA_PRIVILEGE"
 
 	// Variable names
 	ActiveNamespaceVar = "active-namespace"
 	DotfilesPathVar    = "dotfiles-path"
+	PrivilegeVar       = "privilege"
 )
 
 // Placeholder commands - will be implemented later
@@ -102,11 +107,11 @@ func createDefaultConfig() config.DotfilesConfig {
 	conf.Description = "Personal dotfiles configuration"
 
 	// Example dependencies
-	conf.Dependencies = []string{
-		"git",
-		"curl",
-		"vim",
-		"tmux",
+	conf.Dependencies = []config.Dependency{
+		{Name: "git"},
+		{Name: "curl"},
+		{Name: "vim"},
+		{Name: "tmux"},
 	}
 
 	// Environment variables
@@ -211,12 +216,15 @@ var Cmd = &bonzai.Cmd{
 		create.Cmd,    // Create new resources
 		deps.Cmd,      // Manage system dependencies
 		help.Cmd,      // Show help information
+		history.Cmd,   // List recorded runs
 		initCmd,       // Initialize new arara.yaml
 		install.Cmd,   // Install additional tools
 		link.Cmd,      // Create symlinks
 		list.Cmd,      // List available scripts
+		logs.Cmd,      // Show output of a recorded run
 		namespace.Cmd, // Manage namespaces
 		setup.Cmd,     // Core setup operations
+		shellinit.Cmd, // Print shell integration
 		sync.Cmd,      // Sync install scripts
 	},
 	Alias: "ar",
@@ -229,11 +237,14 @@ var Cmd = &bonzai.Cmd{
 - compat:    Check system compatibility for scripts
 - create:    Create new resources (install scripts, build steps)
 - deps:      Manage system dependencies
+- history:   List recorded install and build runs
 - install:   Install additional tools
 - setup:     Core setup operations (backup, link, restore)
 - list:      List available installation scripts
+- logs:      Show the captured output of a run
 - init:      Initialize new arara.yaml configuration
 - namespace: Manage and switch between dotfiles namespaces
+- shell-init: Print PATH, env, completion and prompt setup for a shell
 - help:      Show this help message
 
 Use 'arara help <command> <subcommand>...' for detailed information
@@ -251,6 +262,12 @@ about each command.`,
 			E: DotfilesPathEnv,
 			S: "Path to active dotfiles repository",
 		},
+		{
+			K: PrivilegeVar,
+			V: "",
+			E: PrivilegeEnv,
+			S: "Privilege escalation tool (sudo, doas, run0, pkexec, none, auto)",
+		},
 	},
 	Init: func(x *bonzai.Cmd, args ...string) error {
 		// Load global config
@@ -259,18 +276,18 @@ about each command.`,
 			return fmt.Errorf("failed to load global config: %w", err)
 		}
 
-		// Get active namespace from env/var or use first available
-		ns := bonzaiVars.Fetch(ActiveNamespaceEnv, ActiveNamespaceVar, "")
-		if ns == "" && len(gc.Config.Namespaces) > 0 {
-			ns = gc.Config.Namespaces[0]
-			bonzaiVars.Data.Set(ActiveNamespaceVar, ns)
+		// Resolve the active namespace from env, the arara.yaml above the
+		// working directory, the switched namespace or the first available
+		cwd, _ := os.Getwd()
+		res := gc.ActivateNamespace(cwd)
+		if res.Source == config.FromDefault {
+			bonzaiVars.Data.Set(ActiveNamespaceVar, res.Name)
 		}
 
-		// Set dotfiles path if namespace is active
-		if ns != "" {
-			if info, ok := gc.Config.Configs[ns]; ok {
-				bonzaiVars.Data.Set(DotfilesPathVar, info.Path)
-			}
+		// Set dotfiles path if namespace is active; a namespace found from
+		// the directory only applies inside it and is not persisted
+		if res.Path != "" && res.Source != config.FromDirectory {
+			bonzaiVars.Data.Set(DotfilesPathVar, res.Path)
 		}
 
 		return nil
@@ -8,11 +8,13 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
 )
 
 // CompatSpec defines the compatibility requirements for a script
 type CompatSpec struct {
-	OS     string `yaml:"os"`     // Operating system name (e.g., debian, ubuntu, darwin)
+	OS     string `yaml:"os"`     // Operating system name (e.g., linux, debian, ubuntu, darwin)
 	Arch   string `yaml:"arch"`   // Architecture (e.g., amd64, arm64)
 	Shell  string `yaml:"shell"`  // Shell (e.g., bash, zsh)
 	PkgMgr string `yaml:"pkgmgr"` // Package manager (e.g., apt, yum, pacman)
@@ -54,6 +56,11 @@ func init() {
 			return true // No requirement specified
 		}
 
+		// A kernel name such as linux matches every distribution
+		if strings.EqualFold(runtime.GOOS, value) {
+			return true
+		}
+
 		// Get OS information
 		osInfo, err := getOSInfo()
 		if err != nil {
@@ -112,53 +119,69 @@ func init() {
 	})
 }
 
-// Check validates if the current system environment meets the compatibility requirements
-func Check(compat CompatSpec) bool {
-	// Check OS compatibility
-	if validator, ok := getValidator("os"); ok {
-		if !validator(compat.OS) {
-			return false
-		}
-	}
+// Failure describes a single compatibility requirement that is not met
+type Failure struct {
+	Field string // Validator field (e.g., os, arch, custom)
+	Value string // Required value as written in the spec
+}
 
-	// Check architecture compatibility
-	if validator, ok := getValidator("arch"); ok {
-		if !validator(compat.Arch) {
-			return false
-		}
+// String implements fmt.Stringer for reporting
+func (f Failure) String() string {
+	return fmt.Sprintf("%s: requires %s", f.Field, f.Value)
+}
+
+// FromConfig converts a config.CompatConfig into a CompatSpec
+func FromConfig(c *config.CompatConfig) CompatSpec {
+	if c == nil {
+		return CompatSpec{}
+	}
+	return CompatSpec{
+		OS:     c.OS,
+		Arch:   c.Arch,
+		Shell:  c.Shell,
+		PkgMgr: c.PkgMgr,
+		Kernel: c.Kernel,
+		Custom: c.Custom,
 	}
+}
 
-	// Check shell compatibility
-	if validator, ok := getValidator("shell"); ok {
-		if !validator(compat.Shell) {
-			return false
-		}
+// Failures returns every requirement the current system environment does
+// not meet, in the same order Check evaluates them
+func Failures(compat CompatSpec) []Failure {
+	var failures []Failure
+
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"os", compat.OS},
+		{"arch", compat.Arch},
+		{"shell", compat.Shell},
+		{"pkgmgr", compat.PkgMgr},
+		{"kernel", compat.Kernel},
 	}
 
-	// Check package manager compatibility
-	if validator, ok := getValidator("pkgmgr"); ok {
-		if !validator(compat.PkgMgr) {
-			return false
+	for _, f := range fields {
+		if validator, ok := getValidator(f.name); ok {
+			if !validator(f.value) {
+				failures = append(failures, Failure{Field: f.name, Value: f.value})
+			}
 		}
 	}
 
-	// Check kernel version compatibility
-	if validator, ok := getValidator("kernel"); ok {
-		if !validator(compat.Kernel) {
-			return false
+	// Check custom validators one by one so each failure can be reported
+	for _, req := range compat.Custom {
+		if !CheckCustom([]interface{}{req}) {
+			failures = append(failures, Failure{Field: "custom", Value: customName(req)})
 		}
 	}
 
-	// Check custom validators
-	customReqs := make([]interface{}, 0, len(compat.Custom))
-	customReqs = append(customReqs, compat.Custom...)
-
-	if !CheckCustom(customReqs) {
-		return false
-	}
+	return failures
+}
 
-	// All checks passed
-	return true
+// Check validates if the current system environment meets the compatibility requirements
+func Check(compat CompatSpec) bool {
+	return len(Failures(compat)) == 0
 }
 
 // getOSInfo parses /etc/os-release to get OS information
@@ -200,3 +223,17 @@ func getOSInfo() (map[string]string, error) {
 
 	return osInfo, nil
 }
+
+// Distros returns the IDs of the running distribution, most specific
+// first: the os-release ID followed by each ID_LIKE entry
+func Distros() []string {
+	osInfo, err := getOSInfo()
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	if id := strings.ToLower(osInfo["ID"]); id != "" {
+		ids = append(ids, id)
+	}
+	return append(ids, strings.Fields(strings.ToLower(osInfo["ID_LIKE"]))...)
+}
@@ -34,6 +34,11 @@ func TestOSValidator(t *testing.T) {
 		}
 	}
 
+	// The kernel name matches whatever the distribution
+	if !osValidator(runtime.GOOS) {
+		t.Errorf("OS validator should return true for %s", runtime.GOOS)
+	}
+
 	// Non-existent OS should not match
 	if osValidator("nonexistent-os") {
 		t.Error("OS validator should return false for non-existent OS")
@@ -247,4 +252,31 @@ func TestCheck(t *testing.T) {
 	if Check(invalidCustomSpec) {
 		t.Error("Check should return false for invalid custom validator")
 	}
-}
\ No newline at end of file
+}
+
+// TestFailures tests that unmet requirements are reported individually
+func TestFailures(t *testing.T) {
+	// Empty spec has no failures
+	if failures := Failures(CompatSpec{}); len(failures) != 0 {
+		t.Errorf("Failures should be empty for empty CompatSpec, got %v", failures)
+	}
+
+	spec := CompatSpec{
+		OS:     "nonexistent-os",
+		Arch:   runtime.GOARCH,
+		Custom: []any{"nonexistent-validator"},
+	}
+	failures := Failures(spec)
+	if len(failures) != 2 {
+		t.Fatalf("Failures should report 2 failures, got %v", failures)
+	}
+	if failures[0].Field != "os" || failures[0].Value != "nonexistent-os" {
+		t.Errorf("unexpected first failure: %v", failures[0])
+	}
+	if failures[1].Field != "custom" || failures[1].Value != "nonexistent-validator" {
+		t.Errorf("unexpected second failure: %v", failures[1])
+	}
+	if got := failures[0].String(); got != "os: requires nonexistent-os" {
+		t.Errorf("Failure.String() = %q", got)
+	}
+}
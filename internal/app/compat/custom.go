@@ -119,4 +119,20 @@ func checkStringReq(name string) bool {
 	
 	// Validate with nil value
 	return validator.Validate(nil)
-}
\ No newline at end of file
+}
+
+// customName returns a readable name for a custom requirement
+func customName(req interface{}) string {
+	switch r := req.(type) {
+	case map[string]interface{}:
+		name, _ := r["name"].(string)
+		if value, ok := r["value"]; ok {
+			return fmt.Sprintf("%s=%v", name, value)
+		}
+		return name
+	case string:
+		return r
+	default:
+		return fmt.Sprintf("%v", req)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/BuddhiLW/arara/internal/pkg/blocks"
 	"github.com/BuddhiLW/arara/internal/pkg/config"
 	"github.com/rwxrob/bonzai"
 	"github.com/rwxrob/bonzai/cmds/help"
@@ -279,6 +280,9 @@ var setupPathCmd = &bonzai.Cmd{
 	Long: `
 Add the local-bin directory to PATH in your .bashrc file.
 This ensures executables created with 'arara create bin' are available in your shell.
+The PATH lines are kept in the managed block "local-bin" and rewritten from the
+registered namespaces each time (see 'arara setup blocks' for blocks of your own).
+To set up bash, zsh or fish without editing the file, see 'arara shell-init'.
 `,
 	Do: func(cmd *bonzai.Cmd, args ...string) error {
 		// Get home directory
@@ -309,72 +313,37 @@ This ensures executables created with 'arara create bin' are available in your s
 			return fmt.Errorf("failed to read .bashrc: %w", err)
 		}
 
-		// Check if paths are already in .bashrc
-		if strings.Contains(string(content), "# <<<< arara local-bin setup") {
-			// Extract existing paths between markers
-			start := strings.Index(string(content), "# <<<< arara local-bin setup")
-			end := strings.Index(string(content), "# >>>>")
-			if start == -1 || end == -1 {
-				return fmt.Errorf("malformed arara path setup in .bashrc")
-			}
-
-			existingBlock := string(content[start:end])
-
-			// Check which paths need to be added
-			newPaths := make([]string, 0)
-			for _, path := range localBinPaths {
-				if !strings.Contains(existingBlock, path) {
-					newPaths = append(newPaths, path)
-				}
-			}
-
-			if len(newPaths) == 0 {
-				fmt.Println("All paths already configured in .bashrc")
-				return nil
-			}
-
-			// Create updated content
-			beforeBlock := string(content[:start])
-			afterBlock := string(content[end+6:]) // +4 to skip "# >>>>
-
-			newContent := "# <<<< arara local-bin setup\n"
-			// Keep existing paths
-			for _, line := range strings.Split(existingBlock, "\n") {
-				if strings.Contains(line, "export PATH") {
-					newContent += line + "\n"
-				}
-			}
-			// Add new paths
-			for _, path := range newPaths {
-				newContent += fmt.Sprintf(`export PATH="$PATH:%s"`+"\n", path)
-			}
-			newContent += "# >>>>\n"
-
-			// Write updated content
-			finalContent := beforeBlock + newContent + afterBlock
-			if err := os.WriteFile(bashrcPath, []byte(finalContent), 0644); err != nil {
-				return fmt.Errorf("failed to update .bashrc: %w", err)
-			}
+		// Replace the block written by earlier versions with a managed one
+		updated, migrated, err := blocks.RemoveBetween(string(content), "# <<<< arara local-bin setup", "# >>>>")
+		if err != nil {
+			return fmt.Errorf("malformed arara path setup in .bashrc: %w", err)
+		}
 
-			fmt.Printf("Added new paths to PATH in .bashrc\n")
-			fmt.Println("Please run 'source ~/.bashrc' or start a new shell for changes to take effect")
+		// Without any local-bin directory there is nothing to add to PATH
+		var changed bool
+		if len(localBinPaths) == 0 {
+			updated, changed, err = blocks.Remove(updated, blocks.LocalBin, blocks.Syntax{Start: "#"})
 		} else {
-			// No existing block, create new one
-			newContent := "\n# <<<< arara local-bin setup\n"
+			var body strings.Builder
 			for _, path := range localBinPaths {
-				newContent += fmt.Sprintf(`export PATH="$PATH:%s"`+"\n", path)
-			}
-			newContent += "# >>>>\n"
-
-			// Append to .bashrc
-			if err := os.WriteFile(bashrcPath, append(content, []byte(newContent)...), 0644); err != nil {
-				return fmt.Errorf("failed to update .bashrc: %w", err)
+				fmt.Fprintf(&body, "export PATH=\"$PATH:%s\"\n", path)
 			}
+			updated, changed, err = blocks.Set(updated, blocks.LocalBin, body.String(), blocks.Syntax{Start: "#"})
+		}
+		if err != nil {
+			return fmt.Errorf("malformed arara path setup in .bashrc: %w", err)
+		}
+		if !migrated && !changed {
+			fmt.Println("All paths already configured in .bashrc")
+			return nil
+		}
 
-			fmt.Printf("Added paths to PATH in .bashrc\n")
-			fmt.Println("Please run 'source ~/.bashrc' or start a new shell for changes to take effect")
+		if err := os.WriteFile(bashrcPath, []byte(updated), 0644); err != nil {
+			return fmt.Errorf("failed to update .bashrc: %w", err)
 		}
 
+		fmt.Printf("Updated PATH in .bashrc\n")
+		fmt.Println("Please run 'source ~/.bashrc' or start a new shell for changes to take effect")
 		return nil
 	},
 }
@@ -160,3 +160,71 @@ func TestGetIndentation(t *testing.T) {
 		t.Errorf("Expected default indentation to be '  ', got '%s'", defaultIndent)
 	}
 }
+
+func TestSetupPathCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	gc := &config.GlobalConfig{
+		Config: config.Config{
+			Namespaces: []string{"work", "plain", "personal"},
+			Configs: map[string]config.NSInfo{
+				"work":     {Path: home, LocalBin: "work"},
+				"plain":    {Path: home},
+				"personal": {Path: home, LocalBin: "personal"},
+			},
+		},
+	}
+	oldNewGlobalConfig := config.NewGlobalConfig
+	config.NewGlobalConfig = func() (*config.GlobalConfig, error) {
+		return gc, nil
+	}
+	defer func() {
+		config.NewGlobalConfig = oldNewGlobalConfig
+	}()
+
+	// A .bashrc set up by an earlier version, with a path since removed
+	bashrc := filepath.Join(home, ".bashrc")
+	before := "alias ll='ls -l'\n"
+	after := "export EDITOR=vim\n"
+	legacy := "\n# <<<< arara local-bin setup\n" +
+		`export PATH="$PATH:` + filepath.Join(home, ".local", "bin", "work") + "\"\n" +
+		`export PATH="$PATH:/home/old/.local/bin/gone"` + "\n" +
+		"# >>>>\n"
+	if err := os.WriteFile(bashrc, []byte(before+legacy+after), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setupPathCmd.Do(setupPathCmd); err != nil {
+		t.Fatalf("setupPathCmd.Do() error = %v", err)
+	}
+	data, err := os.ReadFile(bashrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := before + after + "\n" +
+		"# >>> arara local-bin >>>\n" +
+		`export PATH="$PATH:` + filepath.Join(home, ".local", "bin", "work") + "\"\n" +
+		`export PATH="$PATH:` + filepath.Join(home, ".local", "bin", "personal") + "\"\n" +
+		"# <<< arara local-bin <<<\n"
+	if string(data) != want {
+		t.Fatalf(".bashrc:\n%s\nwant:\n%s", data, want)
+	}
+
+	// Running again leaves the file alone
+	if err := setupPathCmd.Do(setupPathCmd); err != nil {
+		t.Fatalf("setupPathCmd.Do() error = %v", err)
+	}
+	if again, _ := os.ReadFile(bashrc); string(again) != want {
+		t.Errorf("second run changed .bashrc:\n%s", again)
+	}
+
+	// Without any local-bin directory the block goes away
+	gc.Config.Namespaces = []string{"plain"}
+	if err := setupPathCmd.Do(setupPathCmd); err != nil {
+		t.Fatalf("setupPathCmd.Do() error = %v", err)
+	}
+	if got, _ := os.ReadFile(bashrc); string(got) != before+after {
+		t.Errorf(".bashrc:\n%s\nwant:\n%s", got, before+after)
+	}
+}
@@ -0,0 +1,199 @@
+package deps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+// A package cache holds the package files of each dependency, with the
+// dependencies it pulled in, under <cache>/<manager>/<package>/ so an
+// install only uses the files fetched for the packages it installs:
+//
+//	cache/
+//	  apt/
+//	    git/git_2.43.0-1_amd64.deb
+//	    git/liberror-perl_0.17029-2_all.deb
+//	  pacman/
+//	    neovim/neovim-0.10.2-1-x86_64.pkg.tar.zst
+
+// Cacher is implemented by package managers that can download packages
+// into a cache and install them from it without network access
+type Cacher interface {
+	// Fetch downloads pkgs with their dependencies into the cache at dir
+	Fetch(dir string, pkgs ...string) error
+
+	// InstallFrom installs (or upgrades) pkgs from the cache at dir
+	InstallFrom(dir string, pkgs ...string) error
+}
+
+// cacherFor returns pm as a Cacher if it supports offline installs
+func cacherFor(pm PackageManager) (Cacher, bool) {
+	m, ok := pm.(*cliManager)
+	if !ok || m.fetch == nil {
+		return nil, false
+	}
+	return m, true
+}
+
+// cachePath returns the cache directory holding the files of pkg
+func (m *cliManager) cachePath(dir, pkg string) string {
+	return filepath.Join(dir, m.name, pkg)
+}
+
+func (m *cliManager) Fetch(dir string, pkgs ...string) error {
+	if m.fetch == nil {
+		return fmt.Errorf("%s does not support offline installs", m.name)
+	}
+	for _, pkg := range pkgs {
+		pkgDir := m.cachePath(dir, pkg)
+		// apt refuses to download without a partial/ directory
+		if err := os.MkdirAll(filepath.Join(pkgDir, "partial"), 0755); err != nil {
+			return fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		if err := m.run(m.fetch(pkgDir), pkg); err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", pkg, err)
+		}
+	}
+	return nil
+}
+
+func (m *cliManager) InstallFrom(dir string, pkgs ...string) error {
+	if m.fetch == nil {
+		return fmt.Errorf("%s does not support offline installs", m.name)
+	}
+	if len(pkgs) == 0 {
+		return nil
+	}
+
+	// Packages shared between dependencies are installed once
+	seen := make(map[string]bool)
+	var files []string
+	for _, pkg := range pkgs {
+		found, err := m.packageFiles(m.cachePath(dir, pkg))
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("%s is not in the package cache %s, run 'arara deps fetch' first", pkg, dir)
+		}
+		for _, file := range found {
+			if !seen[filepath.Base(file)] {
+				seen[filepath.Base(file)] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return m.run(m.installLocal, files...)
+}
+
+// packageFiles returns the package files in dir
+func (m *cliManager) packageFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read package cache: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		for _, ext := range m.packageExts {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ext) {
+				files = append(files, filepath.Join(dir, entry.Name()))
+				break
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// fetchDependencies downloads deps into the cache at dir. Dependencies
+// whose package manager cannot cache packages are skipped with a notice.
+func fetchDependencies(deps []config.Dependency, dir string) error {
+	entries, err := resolveDependencies(deps)
+	if err != nil {
+		return err
+	}
+
+	batches := make(map[string][]string)
+	cachers := make(map[string]Cacher)
+	var order []string
+	for i, entry := range entries {
+		entry, _ = config.SplitConstraint(entry)
+		d := parseDependency(entry)
+		pm, err := lookupManager(d.Manager)
+		if err != nil {
+			return err
+		}
+		c, ok := cacherFor(pm)
+		if !ok {
+			fmt.Fprintf(Stdout, "Skipping %s: %s packages cannot be cached\n", deps[i].Name, pm.Name())
+			continue
+		}
+		if _, ok := cachers[pm.Name()]; !ok {
+			cachers[pm.Name()] = c
+			order = append(order, pm.Name())
+		}
+		batches[pm.Name()] = append(batches[pm.Name()], d.Package)
+	}
+
+	if len(order) == 0 {
+		fmt.Fprintln(Stdout, "Nothing to fetch")
+		return nil
+	}
+	for _, name := range order {
+		fmt.Fprintf(Stdout, "Fetching %d dependencies using %s into %s...\n", len(batches[name]), name, dir)
+		if err := cachers[name].Fetch(dir, batches[name]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheFlag splits the --cache flag off args
+func cacheFlag(args []string) (dir string, rest []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--cache":
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("--cache requires a directory")
+			}
+			i++
+			dir = args[i]
+		case strings.HasPrefix(arg, "--cache="):
+			dir = strings.TrimPrefix(arg, "--cache=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return dir, rest, nil
+}
+
+// packageCache returns the cache directory given by flag, or else the
+// package_cache of the global config; empty if none is configured
+func packageCache(flag string) (string, error) {
+	dir := flag
+	if dir == "" {
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			return "", fmt.Errorf("failed to load global config: %w", err)
+		}
+		dir = gc.PackageCache
+	}
+	if dir == "" {
+		return "", nil
+	}
+	dir = os.ExpandEnv(dir)
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid package cache %s: %w", dir, err)
+	}
+	return abs, nil
+}
@@ -0,0 +1,180 @@
+package deps
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCache creates empty package files in the cache at dir
+func writeCache(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var got []string
+	origRun := runCmd
+	runCmd = func(env []string, args ...string) error {
+		got = append(got, strings.Join(args, " "))
+		return nil
+	}
+	defer func() { runCmd = origRun }()
+
+	dir := t.TempDir()
+	testCases := []struct {
+		pm   string
+		want []string
+	}{
+		{"apt", []string{
+			"sudo apt-get install --download-only -y -o Dir::Cache::archives=" + filepath.Join(dir, "apt", "git") + " git",
+			"sudo apt-get install --download-only -y -o Dir::Cache::archives=" + filepath.Join(dir, "apt", "vim") + " vim",
+		}},
+		{"dnf", []string{
+			"sudo dnf download --resolve --alldeps --destdir " + filepath.Join(dir, "dnf", "git") + " git",
+			"sudo dnf download --resolve --alldeps --destdir " + filepath.Join(dir, "dnf", "vim") + " vim",
+		}},
+		{"pacman", []string{
+			"sudo pacman -Sw --noconfirm --cachedir " + filepath.Join(dir, "pacman", "git") + " git",
+			"sudo pacman -Sw --noconfirm --cachedir " + filepath.Join(dir, "pacman", "vim") + " vim",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pm, func(t *testing.T) {
+			got = nil
+			c, ok := cacherFor(packageManagers[tc.pm])
+			if !ok {
+				t.Fatalf("%s should support offline installs", tc.pm)
+			}
+			if err := c.Fetch(dir, "git", "vim"); err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("ran:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+			if _, err := os.Stat(filepath.Join(dir, tc.pm, "git", "partial")); err != nil {
+				t.Errorf("cache directory not created: %v", err)
+			}
+		})
+	}
+
+	for _, pm := range []string{"brew", "flatpak", "cargo", "go"} {
+		if _, ok := cacherFor(packageManagers[pm]); ok {
+			t.Errorf("%s should not support offline installs", pm)
+		}
+	}
+}
+
+func TestInstallFrom(t *testing.T) {
+	var got []string
+	origRun := runCmd
+	runCmd = func(env []string, args ...string) error {
+		got = append(got, strings.Join(args, " "))
+		return nil
+	}
+	defer func() { runCmd = origRun }()
+
+	dir := t.TempDir()
+	writeCache(t, dir,
+		"pacman/neovim/neovim-0.10.2-1-x86_64.pkg.tar.zst",
+		"pacman/neovim/neovim-0.10.2-1-x86_64.pkg.tar.zst.sig",
+		"pacman/neovim/libuv-1.48.0-2-x86_64.pkg.tar.zst",
+		"pacman/tmux/libuv-1.48.0-2-x86_64.pkg.tar.zst",
+		"pacman/tmux/tmux-3.4-6-x86_64.pkg.tar.zst",
+		"pacman/git/partial/git-2.47.0-1-x86_64.pkg.tar.zst.part",
+	)
+
+	c, _ := cacherFor(packageManagers["pacman"])
+	if err := c.InstallFrom(dir, "neovim", "tmux"); err != nil {
+		t.Fatalf("InstallFrom() error = %v", err)
+	}
+	// Signatures are skipped and shared packages installed once
+	want := "sudo pacman -U --needed --noconfirm " + strings.Join([]string{
+		filepath.Join(dir, "pacman/neovim/libuv-1.48.0-2-x86_64.pkg.tar.zst"),
+		filepath.Join(dir, "pacman/neovim/neovim-0.10.2-1-x86_64.pkg.tar.zst"),
+		filepath.Join(dir, "pacman/tmux/tmux-3.4-6-x86_64.pkg.tar.zst"),
+	}, " ")
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+
+	// Incomplete downloads don't count as cached
+	got = nil
+	err := c.InstallFrom(dir, "git")
+	if err == nil || !strings.Contains(err.Error(), "deps fetch") {
+		t.Errorf("expected missing package error, got %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("nothing should run, ran %q", got)
+	}
+}
+
+func TestInstallDependenciesFromCache(t *testing.T) {
+	var got []string
+	origRun, origLook, origOutput := runCmd, lookPath, outputCmd
+	runCmd = func(env []string, args ...string) error {
+		got = append(got, strings.Join(args, " "))
+		return nil
+	}
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	// Nothing is installed yet
+	outputCmd = func(args ...string) (string, error) {
+		return "", exec.Command("false").Run()
+	}
+	defer func() { runCmd, lookPath, outputCmd = origRun, origLook, origOutput }()
+
+	dir := t.TempDir()
+	writeCache(t, dir, "apt/git/git_2.43.0-1_amd64.deb")
+
+	if _, err := installDependencies(plain("apt:git", "cargo:bat"), dir); err != nil {
+		t.Fatalf("installDependencies() error = %v", err)
+	}
+	want := []string{
+		"sudo apt-get install -y --no-download " + filepath.Join(dir, "apt/git/git_2.43.0-1_amd64.deb"),
+		"cargo install --locked bat", // cannot be cached
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ran:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCacheFlag(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		wantDir  string
+		wantRest []string
+		wantErr  bool
+	}{
+		{args: []string{"git"}, wantRest: []string{"git"}},
+		{args: []string{"--cache", "/mnt/cache", "-g", "dev"}, wantDir: "/mnt/cache", wantRest: []string{"-g", "dev"}},
+		{args: []string{"git", "--cache=/mnt/cache"}, wantDir: "/mnt/cache", wantRest: []string{"git"}},
+		{args: []string{"--cache"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		dir, rest, err := cacheFlag(tc.args)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("cacheFlag(%q) expected error", tc.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("cacheFlag(%q) error = %v", tc.args, err)
+		}
+		if dir != tc.wantDir || strings.Join(rest, " ") != strings.Join(tc.wantRest, " ") {
+			t.Errorf("cacheFlag(%q) = %q, %q", tc.args, dir, rest)
+		}
+	}
+}
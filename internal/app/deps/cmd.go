@@ -8,10 +8,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 
 	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/state"
 	"github.com/rwxrob/bonzai"
 	"github.com/rwxrob/bonzai/cmds/help"
 	bonzaiVars "github.com/rwxrob/bonzai/vars"
@@ -23,46 +23,6 @@ var (
 	Stdout io.Writer = os.Stdout // For capturing output
 )
 
-type PackageManager struct {
-	Name          string
-	InstallCmd    string
-	InstallPrefix []string
-	YesFlag       string // Flag to auto-accept prompts
-}
-
-var packageManagers = map[string]PackageManager{
-	"apt": {
-		Name:          "apt",
-		InstallCmd:    "install",
-		InstallPrefix: []string{"sudo", "apt-get"},
-		YesFlag:       "-y",
-	},
-	"dnf": {
-		Name:          "dnf",
-		InstallCmd:    "install",
-		InstallPrefix: []string{"sudo", "dnf"},
-		YesFlag:       "-y",
-	},
-	"yum": {
-		Name:          "yum",
-		InstallCmd:    "install",
-		InstallPrefix: []string{"sudo", "yum"},
-		YesFlag:       "-y",
-	},
-	"pacman": {
-		Name:          "pacman",
-		InstallCmd:    "-S",
-		InstallPrefix: []string{"sudo", "pacman"},
-		YesFlag:       "--noconfirm",
-	},
-	"brew": {
-		Name:          "brew",
-		InstallCmd:    "install",
-		InstallPrefix: []string{"brew"},
-		YesFlag:       "", // Homebrew doesn't prompt by default
-	},
-}
-
 var Cmd = &bonzai.Cmd{
 	Name:  "deps",
 	Alias: "dependencies",
@@ -76,10 +36,17 @@ This command allows you to:
 - Add new dependencies
 - Remove dependencies
 - Install dependencies using system package manager
+- Fetch packages into a local cache for offline installs
+- Uninstall packages arara installed that are no longer declared
+- Check which dependencies are installed
+- Compare installed versions with arara.lock
+- Import the packages installed on this machine, or export a Brewfile
 
 Dependencies are stored in the active namespace's arara.yaml configuration file.
+When it extends another namespace, the base dependencies are included wherever
+dependencies are read; add, remove, sync and import only edit the local file.
 `,
-	Cmds: []*bonzai.Cmd{syncCmd, listCmd, addCmd, removeCmd, installCmd, help.Cmd},
+	Cmds: []*bonzai.Cmd{syncCmd, listCmd, addCmd, removeCmd, installCmd, fetchCmd, pruneCmd, checkCmd, diffCmd, importCmd, exportCmd, help.Cmd},
 }
 
 var syncCmd = &bonzai.Cmd{
@@ -92,6 +59,7 @@ Sync dependencies from a file with #-commented-deps format to your arara.yaml co
 
 This will read the specified file and extract all non-commented lines as dependencies.
 Lines starting with # or ## are treated as comments and ignored.
+Package name mappings of dependencies that remain in the list are kept.
 
 Usage:
   arara deps sync path/to/deps-file.txt
@@ -116,8 +84,14 @@ Usage:
 			return fmt.Errorf("failed to read dependencies file: %w", err)
 		}
 
+		// Keep per-distro mappings of dependencies that are still listed
+		currentDeps, err := loadDependencies(config.CoreGroup)
+		if err != nil {
+			return err
+		}
+
 		// Update the configuration
-		return saveDependenciesToConfig(deps)
+		return saveDependenciesToConfig(config.CoreGroup, syncDependencies(currentDeps, deps))
 	},
 }
 
@@ -128,22 +102,48 @@ var listCmd = &bonzai.Cmd{
 	Long: `
 List all dependencies stored in the active namespace's arara.yaml configuration.
 
-This will display all dependencies that are currently defined.
+This will display all dependencies that are currently defined, by group.
+Optional groups and groups that are not compatible with this system are
+marked as such.
 `,
 	Do: func(caller *bonzai.Cmd, args ...string) error {
-		deps, err := loadDependencies()
+		groups, err := loadGroups()
 		if err != nil {
 			return err
 		}
 
-		if len(deps) == 0 {
-			fmt.Println("No dependencies found")
+		total := 0
+		for _, g := range groups {
+			total += len(g.Dependencies)
+		}
+		if total == 0 {
+			fmt.Fprintln(Stdout, "No dependencies found")
 			return nil
 		}
 
-		fmt.Println("Dependencies:")
-		for _, dep := range deps {
-			fmt.Printf("  %s\n", dep)
+		fmt.Fprintln(Stdout, "Dependencies:")
+		for _, g := range groups {
+			if len(g.Dependencies) == 0 {
+				continue
+			}
+			header := g.Name
+			if g.Description != "" {
+				header += " - " + g.Description
+			}
+			var notes []string
+			if g.Optional {
+				notes = append(notes, "optional")
+			}
+			if len(g.Failures) > 0 {
+				notes = append(notes, "incompatible: "+g.Failures[0].String())
+			}
+			if len(notes) > 0 {
+				header += " (" + strings.Join(notes, ", ") + ")"
+			}
+			fmt.Fprintf(Stdout, "  %s:\n", header)
+			for _, dep := range g.Dependencies {
+				fmt.Fprintf(Stdout, "    %s\n", dep)
+			}
 		}
 		return nil
 	},
@@ -153,19 +153,30 @@ var addCmd = &bonzai.Cmd{
 	Name:    "add",
 	Alias:   "a",
 	Short:   "add dependencies",
-	Usage:   "add <package1> [package2...]",
+	Usage:   "add [-g <group>] <package1> [package2...]",
 	Long: `
 Add one or more dependencies to the active namespace's arara.yaml configuration.
 
 This will add the specified packages to the dependencies list if they don't already exist.
+With --group the packages are added to that dependency group, which is
+created if needed.
 
 Usage:
   arara deps add git vim tmux
+  arara deps add --group gui flatpak:org.mozilla.firefox
 `,
 	MinArgs: 1,
 	Do: func(caller *bonzai.Cmd, args ...string) error {
+		group, args, err := groupFlag(args)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("no packages given")
+		}
+
 		// Load current dependencies
-		currentDeps, err := loadDependencies()
+		currentDeps, err := loadDependencies(group)
 		if err != nil {
 			return err
 		}
@@ -173,18 +184,18 @@ Usage:
 		// Create a map for fast lookups
 		depsMap := make(map[string]bool)
 		for _, dep := range currentDeps {
-			depsMap[dep] = true
+			depsMap[dep.Name] = true
 		}
 
 		// Add new dependencies
 		added := 0
-		var newDeps []string
+		var newDeps []config.Dependency
 		for _, arg := range args {
 			// Split in case an argument contains multiple packages
 			for _, dep := range strings.Fields(arg) {
-				if !depsMap[dep] {
-					newDeps = append(newDeps, dep)
-					depsMap[dep] = true
+				if !depsMap[config.ParseDependency(dep).Name] {
+					newDeps = append(newDeps, config.ParseDependency(dep))
+					depsMap[config.ParseDependency(dep).Name] = true
 					added++
 				}
 			}
@@ -197,7 +208,7 @@ Usage:
 
 		// Save updated dependencies (add only new ones to current list)
 		allDeps := append(currentDeps, newDeps...)
-		if err := saveDependenciesToConfig(allDeps); err != nil {
+		if err := saveDependenciesToConfig(group, allDeps); err != nil {
 			return err
 		}
 
@@ -210,19 +221,29 @@ var removeCmd = &bonzai.Cmd{
 	Name:    "remove",
 	Alias:   "rm",
 	Short:   "remove dependencies",
-	Usage:   "remove <package1> [package2...]",
+	Usage:   "remove [-g <group>] <package1> [package2...]",
 	Long: `
 Remove one or more dependencies from the active namespace's arara.yaml configuration.
 
 This will remove the specified packages from the dependencies list if they exist.
+With --group the packages are removed from that dependency group.
 
 Usage:
   arara deps remove git vim tmux
+  arara deps remove --group gui flatpak:org.mozilla.firefox
 `,
 	MinArgs: 1,
 	Do: func(caller *bonzai.Cmd, args ...string) error {
+		group, args, err := groupFlag(args)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("no packages given")
+		}
+
 		// Load current dependencies
-		currentDeps, err := loadDependencies()
+		currentDeps, err := loadDependencies(group)
 		if err != nil {
 			return err
 		}
@@ -232,15 +253,15 @@ Usage:
 		for _, arg := range args {
 			// Split in case an argument contains multiple packages
 			for _, dep := range strings.Fields(arg) {
-				toRemove[dep] = true
+				toRemove[config.ParseDependency(dep).Name] = true
 			}
 		}
 
 		// Filter out the dependencies to remove
-		var newDeps []string
+		var newDeps []config.Dependency
 		removed := 0
 		for _, dep := range currentDeps {
-			if !toRemove[dep] {
+			if !toRemove[dep.Name] {
 				newDeps = append(newDeps, dep)
 			} else {
 				removed++
@@ -253,7 +274,7 @@ Usage:
 		}
 
 		// Save updated dependencies
-		if err := saveDependenciesToConfig(newDeps); err != nil {
+		if err := saveDependenciesToConfig(group, newDeps); err != nil {
 			return err
 		}
 
@@ -266,7 +287,7 @@ var installCmd = &bonzai.Cmd{
 	Name:  "install",
 	Alias: "i",
 	Short: "install dependencies",
-	Usage: "install [package1 package2...]",
+	Usage: "install [--cache DIR] [-g <group>]... [package...]",
 	Long: `
 Install dependencies using the system's package manager.
 
@@ -275,87 +296,525 @@ active namespace's arara.yaml configuration.
 
 If specific packages are provided as arguments, only those packages will be installed.
 
+Packages that are already installed are skipped (see 'arara deps check').
+
+Dependencies may carry a version constraint, written after the name or
+in the version field of the object form. Installed packages that don't
+satisfy their constraint are upgraded; the install fails if the upgraded
+version still doesn't satisfy it:
+
+  dependencies:
+    - neovim>=0.9,<0.11
+    - name: fd
+      version: ">=8"
+      apt: fd-find
+
+After installing all dependencies the installed versions are recorded in
+arara.lock next to arara.yaml (see 'arara deps diff').
+
+Packages that were missing and got installed are remembered for the
+active namespace, so 'arara deps prune' can remove them once they are no
+longer declared.
+
 Supported package managers:
   - apt (Debian, Ubuntu)
   - dnf (Fedora)
   - yum (CentOS, RHEL)
   - pacman (Arch Linux)
+  - zypper (openSUSE)
+  - apk (Alpine)
+  - xbps (Void)
+  - emerge (Gentoo)
+  - nix (NixOS, or any system with nix-env)
   - brew (macOS)
+  - cask, flatpak, snap (only when named explicitly)
+
+Language package managers are used when named explicitly:
+  - cargo (cargo install)
+  - pipx (pipx install)
+  - go (go install, defaults to @latest)
+  - npm (npm install --global)
+
+The system package manager is detected automatically. A dependency can
+name its package manager explicitly with a "manager:" prefix:
+
+  dependencies:
+    - git
+    - flatpak:org.mozilla.firefox
+    - snap:spotify
+    - cask:firefox
+    - cargo:ripgrep
+    - pipx:black
+    - go:golang.org/x/tools/gopls@latest
+
+Package names that differ between systems can be mapped per package
+manager or per distro (os-release ID or ID_LIKE). Distro mappings win
+over manager mappings, which win over the default. Installing fails if
+no mapping matches and there is no default:
+
+  dependencies:
+    - name: fd
+      apt: fd-find
+      pacman: fd
+      dnf: fd-find
+    - name: pip
+      distro:
+        ubuntu: python3-pip
+        arch: python-pip
+      default: python3-pip
+
+Dependencies can be organized into named groups. The top-level
+dependencies form the core group. Without --group, every group that is
+not optional and compatible with this system is installed. With
+--group (repeatable or comma separated) only those groups are installed:
+
+  dependency_groups:
+    gui:
+      description: Desktop applications
+      optional: true
+      compat:
+        os: linux
+      dependencies:
+        - flatpak:org.mozilla.firefox
+    fonts:
+      dependencies:
+        - ttf-jetbrains-mono
+
+Machines without network access can install from a package cache
+filled by 'arara deps fetch', given with --cache or as package_cache in
+the global config. apt, dnf, yum and pacman packages are installed from
+the cache; other package managers still use the network.
 
 Usage:
-  arara deps install           # Install all dependencies from config
-  arara deps install git tmux  # Install specific packages
+  arara deps install             # Install all default groups from config
+  arara deps install --group gui # Install the gui group
+  arara deps install git tmux    # Install specific packages
+  arara deps install --cache /mnt/usb/arara-cache
 `,
 	Do: func(caller *bonzai.Cmd, args ...string) error {
-		var deps []string
-		var err error
+		cacheArg, args, err := cacheFlag(args)
+		if err != nil {
+			return err
+		}
+		cache, err := packageCache(cacheArg)
+		if err != nil {
+			return err
+		}
 
-		// If no args provided, load all dependencies from config
-		if len(args) == 0 {
-			deps, err = loadDependencies()
-			if err != nil {
-				return err
+		deps, fromConfig, err := argDependencies(args)
+		if err != nil {
+			return err
+		}
+		if len(deps) == 0 {
+			fmt.Fprintln(Stdout, "No dependencies found to install")
+			return nil
+		}
+
+		statuses, err := installDependencies(deps, cache)
+		if err != nil {
+			return err
+		}
+		if err := recordInstalled(activeNamespace(), statuses); err != nil {
+			return err
+		}
+
+		// Only dependencies from arara.yaml are locked
+		if !fromConfig {
+			return nil
+		}
+		path, err := lockPath()
+		if err != nil {
+			return err
+		}
+		lock := newLock(statuses)
+		if old, err := readLock(path); err == nil {
+			lock = mergeLock(old, lock)
+		}
+		if err := writeLock(path, lock); err != nil {
+			return err
+		}
+		fmt.Fprintf(Stdout, "Wrote %s\n", path)
+		return nil
+	},
+}
+
+var fetchCmd = &bonzai.Cmd{
+	Name:  "fetch",
+	Short: "download dependencies into a package cache",
+	Usage: "fetch [--cache DIR] [-g <group>]... [package...]",
+	Long: `
+Download the package files of dependencies, with the packages they
+depend on, into a local package cache for 'arara deps install --cache'
+on machines without network access. Run it on a connected machine with
+the same distribution and package manager as the target machines.
+
+The cache is the --cache directory or package_cache in the global
+config. Each package is kept in <cache>/<manager>/<package>/, so the
+cache can be filled for several groups or namespaces and copied as is.
+
+Supported package managers:
+  - apt (apt-get install --download-only)
+  - dnf (dnf download --resolve --alldeps)
+  - yum (yumdownloader --resolve)
+  - pacman (pacman -Sw)
+
+apt and pacman only download dependencies that are not installed on the
+fetching machine, so fetch from a minimal installation of the target
+system. Dependencies of other package managers are skipped.
+
+Usage:
+  arara deps fetch --cache /mnt/usb/arara-cache
+  arara deps fetch --cache ./cache --group dev
+`,
+	Do: func(caller *bonzai.Cmd, args ...string) error {
+		cacheArg, args, err := cacheFlag(args)
+		if err != nil {
+			return err
+		}
+		cache, err := packageCache(cacheArg)
+		if err != nil {
+			return err
+		}
+		if cache == "" {
+			return fmt.Errorf("no package cache configured, pass --cache DIR or set package_cache in the global config")
+		}
+
+		deps, _, err := argDependencies(args)
+		if err != nil {
+			return err
+		}
+		if len(deps) == 0 {
+			fmt.Fprintln(Stdout, "No dependencies found to fetch")
+			return nil
+		}
+		return fetchDependencies(deps, cache)
+	},
+}
+
+var pruneCmd = &bonzai.Cmd{
+	Name:  "prune",
+	Short: "uninstall packages no longer declared",
+	Usage: "prune [-n|--dry-run] [-y|--yes]",
+	Long: `
+Uninstall the packages that 'arara deps install' installed for the active
+namespace but that are no longer declared in any dependency group of its
+arara.yaml, for example after 'arara deps remove'.
+
+Only packages arara installed are considered: packages that were already
+installed when 'deps install' ran, or installed by other means, are never
+removed. A package installed for another namespace as well is kept and
+only forgotten for this one. The packages are listed and removed after
+confirmation.
+
+Flags:
+  -n, --dry-run  list the packages without removing them
+  -y, --yes      remove without asking
+
+Installed packages are recorded in $XDG_STATE_HOME/arara/state.yaml.
+`,
+	Do: func(caller *bonzai.Cmd, args ...string) error {
+		var dryRun, yes bool
+		for _, arg := range args {
+			switch arg {
+			case "-n", "--dry-run":
+				dryRun = true
+			case "-y", "--yes":
+				yes = true
+			default:
+				return fmt.Errorf("unknown flag: %s", arg)
 			}
+		}
+
+		ns := activeNamespace()
+		if ns == "" {
+			return fmt.Errorf("no active namespace set. Use 'arara namespace switch <n>' first")
+		}
+		groups, err := loadGroups()
+		if err != nil {
+			return err
+		}
+		st, err := state.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load state: %w", err)
+		}
+		return pruneDependencies(st, ns, declaredNames(groups), dryRun, yes)
+	},
+}
+
+var checkCmd = &bonzai.Cmd{
+	Name:  "check",
+	Alias: "c",
+	Short: "check which dependencies are installed",
+	Usage: "check [-g <group>]... [package1 package2...]",
+	Long: `
+Check which dependencies are installed by querying their package manager
+(dpkg-query, rpm -q, pacman -Q, brew list, ...).
+
+Each dependency is reported as installed with its version, or as missing.
+Dependencies whose installed version doesn't satisfy their constraint
+are reported as well. Exits with an error if any dependency is missing
+or unsatisfied, so it can be used in scripts:
+
+  arara deps check || arara deps install
+
+If specific packages are provided as arguments, only those are checked.
+With --group only the named dependency groups are checked.
+`,
+	Do: func(caller *bonzai.Cmd, args ...string) error {
+		deps, _, err := argDependencies(args)
+		if err != nil {
+			return err
+		}
 
-			if len(deps) == 0 {
-				fmt.Println("No dependencies found to install")
-				return nil
+		if len(deps) == 0 {
+			fmt.Fprintln(Stdout, "No dependencies found")
+			return nil
+		}
+
+		statuses, err := checkDependencies(deps)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(Stdout, "Dependencies:")
+		if missing := printStatuses(statuses); missing > 0 {
+			return fmt.Errorf("%d of %d dependencies missing", missing, len(statuses))
+		}
+		fmt.Fprintf(Stdout, "All %d dependencies installed\n", len(statuses))
+		return nil
+	},
+}
+
+var diffCmd = &bonzai.Cmd{
+	Name:  "diff",
+	Alias: "d",
+	Short: "show how this machine deviates from arara.lock",
+	Long: `
+Compare the installed versions of the locked dependencies with the
+versions recorded in arara.lock by the last 'arara deps install':
+
+  changed   installed version differs from the locked one
+  missing   locked package is no longer installed
+  unlocked  dependency declared in arara.yaml but not in arara.lock
+  stale     locked dependency no longer declared in arara.yaml
+`,
+	Do: func(caller *bonzai.Cmd, args ...string) error {
+		path, err := lockPath()
+		if err != nil {
+			return err
+		}
+		lock, err := readLock(path)
+		if err != nil {
+			return err
+		}
+		groups, err := loadGroups()
+		if err != nil {
+			return err
+		}
+
+		diffs, err := diffLock(lock, declaredNames(groups))
+		if err != nil {
+			return err
+		}
+		if len(diffs) == 0 {
+			fmt.Fprintf(Stdout, "No differences from %s\n", LockFile)
+			return nil
+		}
+
+		width := 0
+		for _, d := range diffs {
+			width = max(width, len(d.Name))
+		}
+		for _, d := range diffs {
+			fmt.Fprintf(Stdout, "  %-8s  %-*s  %s\n", d.Kind, width, d.Name, d)
+		}
+		return nil
+	},
+}
+
+var importCmd = &bonzai.Cmd{
+	Name:    "import",
+	Short:   "import installed packages as dependencies",
+	Usage:   "import [-n] [-g <group>] <source> [Brewfile]",
+	MinArgs: 1,
+	Long: `
+Add the packages explicitly installed on this machine to the active
+namespace's arara.yaml, to bootstrap a configuration from an existing
+system. Packages already listed in the group are left alone.
+
+Sources:
+  pacman    pacman -Qqe
+  apt       apt-mark showmanual
+  dnf       dnf history userinstalled
+  brew      brew leaves --installed-on-request and installed casks
+  brewfile  formulae and casks of a Brewfile (default ./Brewfile)
+
+Casks are imported as cask:<name>, so they install and export as casks.
+
+Flags:
+  -n, --dry-run      print the packages without saving them
+  -g, --group NAME   add to a dependency group instead of core
+
+Usage:
+  arara deps import pacman
+  arara deps import --group gui brewfile ~/Brewfile
+`,
+	Do: func(caller *bonzai.Cmd, args ...string) error {
+		group, args, err := groupFlag(args)
+		if err != nil {
+			return err
+		}
+		dryRun := false
+		var rest []string
+		for _, arg := range args {
+			if arg == "-n" || arg == "--dry-run" {
+				dryRun = true
+				continue
 			}
-		} else {
-			// Process args to get individual packages
-			for _, arg := range args {
-				// Split in case an argument contains multiple packages
-				for _, dep := range strings.Fields(arg) {
-					if dep != "" {
-						deps = append(deps, dep)
-					}
-				}
+			rest = append(rest, arg)
+		}
+		if len(rest) == 0 || len(rest) > 2 {
+			return fmt.Errorf("usage: %s", caller.Usage)
+		}
+		source, path := rest[0], ""
+		if len(rest) == 2 {
+			path = rest[1]
+		}
+
+		pkgs, skipped, err := importPackages(source, path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range skipped {
+			fmt.Fprintf(Stdout, "Skipping: %s\n", entry)
+		}
+
+		if dryRun {
+			for _, pkg := range pkgs {
+				fmt.Fprintln(Stdout, pkg)
 			}
+			return nil
 		}
 
-		// Detect package manager
-		pm, err := detectPackageManager()
+		currentDeps, err := loadDependencies(group)
 		if err != nil {
 			return err
 		}
+		listed := make(map[string]bool)
+		for _, dep := range currentDeps {
+			listed[dep.Name] = true
+		}
+		allDeps := currentDeps
+		added := 0
+		for _, pkg := range pkgs {
+			if !listed[pkg] {
+				listed[pkg] = true
+				allDeps = append(allDeps, config.Dependency{Name: pkg})
+				added++
+			}
+		}
 
-		// Run install command
-		fmt.Printf("Installing %d dependencies using %s...\n", len(deps), pm.Name)
+		if added == 0 {
+			fmt.Fprintf(Stdout, "All %d packages from %s already in dependencies list\n", len(pkgs), source)
+			return nil
+		}
+		if err := saveDependenciesToConfig(group, allDeps); err != nil {
+			return err
+		}
+		fmt.Fprintf(Stdout, "Imported %d of %d packages from %s into %s\n", added, len(pkgs), source, group)
+		return nil
+	},
+}
+
+var exportCmd = &bonzai.Cmd{
+	Name:    "export",
+	Short:   "export dependencies as a Brewfile or list",
+	Usage:   "export [-g <group>]... <brewfile|list> [file]",
+	MinArgs: 1,
+	Long: `
+Write the dependencies of the active namespace in another format, to
+standard output or to the given file.
 
-		// Build command with the yes flag if available
-		cmdArgs := append(pm.InstallPrefix, pm.InstallCmd)
+Formats:
+  brewfile  a Brewfile for 'brew bundle', using brew package mappings
+            and writing cask: dependencies as casks; dependencies for
+            other package managers are noted as comments
+  list      one dependency per line with the groups as comments, as read
+            by 'arara deps sync'
 
-		// Add yes flag if provided
-		if pm.YesFlag != "" {
-			cmdArgs = append(cmdArgs, pm.YesFlag)
+All groups are exported unless --group is given.
+
+Usage:
+  arara deps export brewfile ~/Brewfile
+  arara deps export --group core list
+`,
+	Do: func(caller *bonzai.Cmd, args ...string) error {
+		names, args, err := parseGroupFlags(args)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 || len(args) > 2 {
+			return fmt.Errorf("usage: %s", caller.Usage)
 		}
 
-		// Add all dependencies
-		cmdArgs = append(cmdArgs, deps...)
+		groups, err := loadGroups()
+		if err != nil {
+			return err
+		}
+		if len(names) > 0 {
+			byName := make(map[string]depGroup)
+			for _, g := range groups {
+				byName[g.Name] = g
+			}
+			groups = nil
+			for _, name := range names {
+				g, ok := byName[name]
+				if !ok {
+					return fmt.Errorf("unknown dependency group: %s", name)
+				}
+				groups = append(groups, g)
+			}
+		}
 
-		fmt.Printf("Running: %s\n", strings.Join(cmdArgs, " "))
-		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-		
-		// Set environment variables to avoid interactive prompts
-		env := os.Environ()
-		
-		// For Debian/Ubuntu packages (avoid debconf prompts)
-		env = append(env, "DEBIAN_FRONTEND=noninteractive")
-		
-		// For tzdata and other packages that ask for input
-		env = append(env, "DEBCONF_NONINTERACTIVE_SEEN=true")
-		env = append(env, "DEBCONF_NOWARNINGS=yes")
-		
-		// Apply the environment
-		cmd.Env = env
-		
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		if len(args) == 1 {
+			return exportDependencies(Stdout, args[0], groups)
+		}
 
-		return cmd.Run()
+		var buf bytes.Buffer
+		if err := exportDependencies(&buf, args[0], groups); err != nil {
+			return err
+		}
+		if err := os.WriteFile(args[1], buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", args[1], err)
+		}
+		fmt.Fprintf(Stdout, "Exported dependencies to %s\n", args[1])
+		return nil
 	},
 }
 
+// syncDependencies returns names as dependencies, keeping the package
+// mappings of current dependencies with the same name
+func syncDependencies(current []config.Dependency, names []string) []config.Dependency {
+	byName := make(map[string]config.Dependency)
+	for _, dep := range current {
+		byName[dep.Name] = dep
+	}
+
+	deps := make([]config.Dependency, 0, len(names))
+	for _, name := range names {
+		parsed := config.ParseDependency(name)
+		if dep, ok := byName[parsed.Name]; ok {
+			if parsed.Version != "" {
+				dep.Version = parsed.Version
+			}
+			deps = append(deps, dep)
+			continue
+		}
+		deps = append(deps, parsed)
+	}
+	return deps
+}
+
 // readDepsFile reads a dependency file and returns a list of dependencies
 // ignoring lines that start with # or ##
 // Each dependency should be on its own line or separated by spaces
@@ -394,8 +853,43 @@ func readDepsFile(path string) ([]string, error) {
 	return deps, nil
 }
 
-// loadDependencies loads the dependencies from the active namespace's arara.yaml
-func loadDependencies() ([]string, error) {
+// argDependencies returns the packages named in args or, if only --group
+// flags are given, the dependencies of the selected groups in arara.yaml
+func argDependencies(args []string) (deps []config.Dependency, fromConfig bool, err error) {
+	groups, args, err := parseGroupFlags(args)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(args) > 0 {
+		if len(groups) > 0 {
+			return nil, false, fmt.Errorf("--group cannot be combined with package names")
+		}
+		for _, arg := range args {
+			// Split in case an argument contains multiple packages
+			for _, dep := range strings.Fields(arg) {
+				deps = append(deps, config.ParseDependency(dep))
+			}
+		}
+		return deps, false, nil
+	}
+
+	all, err := loadGroups()
+	if err != nil {
+		return nil, false, err
+	}
+	deps, err = selectDependencies(all, groups)
+	return deps, true, err
+}
+
+// activeNamespace returns the name of the active namespace, if any
+func activeNamespace() string {
+	return bonzaiVars.Fetch("ARARA_ACTIVE_NAMESPACE", "active-namespace", "")
+}
+
+// loadConfig loads the active namespace's arara.yaml, merged with the
+// namespace it extends unless the result is to be edited
+func loadConfig(merged bool) (*config.DotfilesConfig, error) {
 	// Get the active namespace
 	activeNS := bonzaiVars.Fetch("ARARA_ACTIVE_NAMESPACE", "active-namespace", "")
 	if activeNS == "" {
@@ -412,27 +906,46 @@ func loadDependencies() ([]string, error) {
 	}
 
 	// Load the configuration
-	cfg, err := config.LoadConfig(filepath.Join(dotfilesPath, "arara.yaml"))
+	load := config.LoadConfig
+	if merged {
+		load = config.LoadMergedConfig
+	}
+	cfg, err := load(filepath.Join(dotfilesPath, "arara.yaml"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config for namespace %s: %w", activeNS, err)
 	}
+	return cfg, nil
+}
 
-	// Process dependencies to ensure each entry is a single package
-	var flatDeps []string
-	for _, dep := range cfg.Dependencies {
-		// Split any multi-word dependencies into individual packages
-		for _, singleDep := range strings.Fields(dep) {
-			if singleDep != "" {
-				flatDeps = append(flatDeps, singleDep)
-			}
-		}
+// loadGroups loads the dependency groups from the active namespace's
+// arara.yaml, including those of the namespace it extends
+func loadGroups() ([]depGroup, error) {
+	cfg, err := loadConfig(true)
+	if err != nil {
+		return nil, err
 	}
+	return configGroups(cfg), nil
+}
 
-	return flatDeps, nil
+// loadDependencies loads the dependencies of group declared in the active
+// namespace's own arara.yaml, for editing. Unknown groups have no
+// dependencies.
+func loadDependencies(group string) ([]config.Dependency, error) {
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range configGroups(cfg) {
+		if g.Name == group {
+			return g.Dependencies, nil
+		}
+	}
+	return nil, nil
 }
 
-// saveDependenciesToConfig saves dependencies to the active namespace's arara.yaml
-func saveDependenciesToConfig(deps []string) error {
+// saveDependenciesToConfig saves the dependencies of group to the active
+// namespace's arara.yaml, creating the group if needed
+func saveDependenciesToConfig(group string, deps []config.Dependency) error {
 	// Get the active namespace
 	activeNS := bonzaiVars.Fetch("ARARA_ACTIVE_NAMESPACE", "active-namespace", "")
 	if activeNS == "" {
@@ -470,7 +983,21 @@ func saveDependenciesToConfig(deps []string) error {
 	}
 
 	// Update dependencies
-	cfg.Dependencies = deps
+	if group == config.CoreGroup {
+		cfg.Dependencies = deps
+		// The loaded core group included these as well
+		if g, ok := cfg.DependencyGroups[config.CoreGroup]; ok {
+			g.Dependencies = nil
+			cfg.DependencyGroups[config.CoreGroup] = g
+		}
+	} else {
+		if cfg.DependencyGroups == nil {
+			cfg.DependencyGroups = make(map[string]config.DependencyGroup)
+		}
+		g := cfg.DependencyGroups[group]
+		g.Dependencies = deps
+		cfg.DependencyGroups[group] = g
+	}
 
 	// Check for concurrent modifications
 	if modified, err := tx.checkModified(); err != nil {
@@ -498,41 +1025,6 @@ func saveDependenciesToConfig(deps []string) error {
 	return nil
 }
 
-// detectPackageManager detects which package manager is available on the system
-func detectPackageManager() (PackageManager, error) {
-	// On macOS, prefer brew
-	if runtime.GOOS == "darwin" {
-		if _, err := exec.LookPath("brew"); err == nil {
-			return packageManagers["brew"], nil
-		}
-	}
-
-	// Check for Linux package managers
-	if runtime.GOOS == "linux" {
-		// Check for pacman (Arch)
-		if _, err := exec.LookPath("pacman"); err == nil {
-			return packageManagers["pacman"], nil
-		}
-
-		// Check for apt (Debian/Ubuntu)
-		if _, err := exec.LookPath("apt-get"); err == nil {
-			return packageManagers["apt"], nil
-		}
-
-		// Check for dnf (Fedora)
-		if _, err := exec.LookPath("dnf"); err == nil {
-			return packageManagers["dnf"], nil
-		}
-
-		// Check for yum (CentOS/RHEL)
-		if _, err := exec.LookPath("yum"); err == nil {
-			return packageManagers["yum"], nil
-		}
-	}
-
-	return PackageManager{}, fmt.Errorf("no supported package manager found")
-}
-
 // transaction handles atomic updates to arara.yaml
 type transaction struct {
 	configPath string
@@ -0,0 +1,353 @@
+package deps
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/BuddhiLW/arara/internal/app/compat"
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+// lookPath finds executables; replaced in tests
+var lookPath = exec.LookPath
+
+// osDistros returns the distro IDs of this system; replaced in tests
+var osDistros = compat.Distros
+
+// Dependency is a package to install, optionally tied to a package manager
+type Dependency struct {
+	Manager string // empty means the detected system package manager
+	Package string
+}
+
+// parseDependency parses a dependency entry. Entries may name their
+// package manager explicitly as "manager:package", for example
+// "flatpak:org.mozilla.firefox", "cargo:ripgrep", "pipx:black" or
+// "go:golang.org/x/tools/gopls@latest". Prefixes that are not a known package
+// manager are kept as part of the package name (e.g. "libc6:i386").
+func parseDependency(entry string) Dependency {
+	if name, pkg, ok := strings.Cut(entry, ":"); ok && pkg != "" {
+		if _, known := packageManagers[name]; known {
+			return Dependency{Manager: name, Package: pkg}
+		}
+	}
+	return Dependency{Package: entry}
+}
+
+// String returns the dependency in the form it is written in arara.yaml
+func (d Dependency) String() string {
+	if d.Manager == "" {
+		return d.Package
+	}
+	return d.Manager + ":" + d.Package
+}
+
+// lookupManager returns the named package manager if it is available on
+// this system. An empty name selects the detected system package manager.
+func lookupManager(name string) (PackageManager, error) {
+	if name == "" {
+		return detectPackageManager()
+	}
+	pm, ok := packageManagers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown package manager: %s", name)
+	}
+	if bin := executable(pm); bin != "" {
+		if _, err := lookPath(bin); err != nil {
+			return nil, fmt.Errorf("package manager %s is not installed (%s not found)", name, bin)
+		}
+	}
+	return pm, nil
+}
+
+// executable returns the command pm needs on PATH, if it declares one
+func executable(pm PackageManager) string {
+	if e, ok := pm.(interface{ executable() string }); ok {
+		return e.executable()
+	}
+	return ""
+}
+
+// detectPackageManager detects which package manager is available on the system
+func detectPackageManager() (PackageManager, error) {
+	for _, name := range detectOrder[runtime.GOOS] {
+		pm := packageManagers[name]
+		if bin := executable(pm); bin != "" {
+			if _, err := lookPath(bin); err != nil {
+				continue
+			}
+		}
+		return pm, nil
+	}
+	return nil, fmt.Errorf("no supported package manager found")
+}
+
+// depStatus is the installed state of a dependency on this system
+type depStatus struct {
+	Name        string         // logical name as written in arara.yaml
+	Dependency                 // resolved manager and package
+	Constraint  string         // version constraint, if any
+	Manager     PackageManager // nil if the manager is not available
+	Version     string         // installed version, empty if missing
+	Unsatisfied bool           // installed version doesn't meet Constraint
+	Err         error          // why the state could not be determined
+	Installed   bool           // installed by installDependencies
+}
+
+// Missing reports whether the dependency needs to be installed
+func (s depStatus) Missing() bool {
+	return s.Version == ""
+}
+
+// OK reports whether the dependency is installed in an acceptable version
+func (s depStatus) OK() bool {
+	return s.Err == nil && !s.Missing() && !s.Unsatisfied
+}
+
+// checkDependencies resolves deps and queries their package managers for
+// the installed version of each, checking it against its constraint
+func checkDependencies(deps []config.Dependency) ([]depStatus, error) {
+	entries, err := resolveDependencies(deps)
+	if err != nil {
+		return nil, err
+	}
+
+	managers := make(map[string]PackageManager)
+	failed := make(map[string]error)
+	statuses := make([]depStatus, 0, len(entries))
+	for i, entry := range entries {
+		// A mapping may carry its own constraint ("apt: neovim>=0.7")
+		entry, constraint := config.SplitConstraint(entry)
+		if constraint == "" {
+			constraint = deps[i].Version
+		}
+		s := depStatus{
+			Name:       deps[i].Name,
+			Dependency: parseDependency(entry),
+			Constraint: constraint,
+		}
+
+		pm, ok := managers[s.Dependency.Manager]
+		if !ok && failed[s.Dependency.Manager] == nil {
+			pm, err = lookupManager(s.Dependency.Manager)
+			if err != nil {
+				failed[s.Dependency.Manager] = err
+			} else {
+				managers[s.Dependency.Manager] = pm
+			}
+		}
+		if pm == nil {
+			s.Err = failed[s.Dependency.Manager]
+			statuses = append(statuses, s)
+			continue
+		}
+
+		s.Manager = pm
+		s.Version, s.Err = pm.InstalledVersion(s.Package)
+		if s.Err == nil && !s.Missing() && s.Constraint != "" {
+			ok, err := satisfies(s.Version, s.Constraint)
+			if err != nil {
+				s.Err = fmt.Errorf("%s: %w", s.Name, err)
+			}
+			s.Unsatisfied = !ok
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// MissingPackages returns those of entries, written as in arara.yaml
+// ("curl", "cargo:ripgrep", "neovim>=0.7"), that are not installed in an
+// acceptable version or whose state could not be determined
+func MissingPackages(entries []string) ([]string, error) {
+	deps := make([]config.Dependency, len(entries))
+	for i, entry := range entries {
+		deps[i] = config.ParseDependency(entry)
+	}
+	statuses, err := checkDependencies(deps)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for i, s := range statuses {
+		if !s.OK() {
+			missing = append(missing, entries[i])
+		}
+	}
+	return missing, nil
+}
+
+// printStatuses writes one line per dependency and returns the number of
+// dependencies that are missing or don't satisfy their constraint
+func printStatuses(statuses []depStatus) int {
+	width := 0
+	for _, s := range statuses {
+		width = max(width, len(s.label()))
+	}
+
+	problems := 0
+	for _, s := range statuses {
+		manager := s.Dependency.Manager
+		if s.Manager != nil {
+			manager = s.Manager.Name()
+		}
+		if s.Constraint != "" {
+			manager += ", wants " + s.Constraint
+		}
+		switch {
+		case s.Err != nil:
+			problems++
+			fmt.Fprintf(Stdout, "  ? %-*s  %s\n", width, s.label(), s.Err)
+		case s.Missing():
+			problems++
+			fmt.Fprintf(Stdout, "  ✗ %-*s  missing (%s)\n", width, s.label(), manager)
+		case s.Unsatisfied:
+			problems++
+			fmt.Fprintf(Stdout, "  ✗ %-*s  %s (%s)\n", width, s.label(), s.Version, manager)
+		default:
+			fmt.Fprintf(Stdout, "  ✓ %-*s  %s (%s)\n", width, s.label(), s.Version, manager)
+		}
+	}
+	return problems
+}
+
+// label names the dependency, showing the package it resolved to when it
+// differs from the logical name
+func (s depStatus) label() string {
+	if s.Dependency.String() == s.Name {
+		return s.Name
+	}
+	return s.Name + " → " + s.Dependency.String()
+}
+
+// installDependencies installs the deps that are not installed yet and
+// upgrades those whose installed version doesn't satisfy their constraint,
+// grouped by package manager in the order each manager first appears.
+// Dependencies with package name mappings are resolved against the
+// system package manager. With a cache directory, packages are installed
+// from the package cache where the manager supports it. Returns the state
+// after installing.
+func installDependencies(deps []config.Dependency, cache string) ([]depStatus, error) {
+	statuses, err := checkDependencies(deps)
+	if err != nil {
+		return nil, err
+	}
+
+	type batch struct {
+		pm      PackageManager
+		install []string
+		upgrade []string
+	}
+	batches := make(map[string]*batch)
+	var order []string
+	ok := 0
+	for _, s := range statuses {
+		if s.Manager == nil {
+			return nil, s.Err
+		}
+		if s.OK() {
+			ok++
+			continue
+		}
+		name := s.Manager.Name()
+		if batches[name] == nil {
+			batches[name] = &batch{pm: s.Manager}
+			order = append(order, name)
+		}
+		// Query errors are treated as missing; the install reports them
+		if s.Err == nil && s.Unsatisfied {
+			batches[name].upgrade = append(batches[name].upgrade, s.Package)
+		} else {
+			batches[name].install = append(batches[name].install, s.Package)
+		}
+	}
+
+	if ok > 0 {
+		fmt.Fprintf(Stdout, "%d of %d dependencies already installed\n", ok, len(statuses))
+	}
+	if len(order) == 0 {
+		fmt.Fprintln(Stdout, "Nothing to install")
+		return statuses, nil
+	}
+
+	for _, name := range order {
+		b := batches[name]
+		if cache != "" {
+			if c, ok := cacherFor(b.pm); ok {
+				// Installing a newer package file upgrades it
+				pkgs := append(b.install, b.upgrade...)
+				fmt.Fprintf(Stdout, "Installing %d dependencies using %s from %s...\n", len(pkgs), name, cache)
+				if err := c.InstallFrom(cache, pkgs...); err != nil {
+					return nil, fmt.Errorf("failed to install dependencies with %s: %w", name, err)
+				}
+				continue
+			}
+			fmt.Fprintf(Stdout, "%s packages cannot be cached, installing from the network\n", name)
+		}
+		if len(b.install) > 0 {
+			fmt.Fprintf(Stdout, "Installing %d dependencies using %s...\n", len(b.install), name)
+			if err := b.pm.Install(b.install...); err != nil {
+				return nil, fmt.Errorf("failed to install dependencies with %s: %w", name, err)
+			}
+		}
+		if len(b.upgrade) > 0 {
+			fmt.Fprintf(Stdout, "Upgrading %d dependencies using %s...\n", len(b.upgrade), name)
+			if err := b.pm.Update(b.upgrade...); err != nil {
+				return nil, fmt.Errorf("failed to upgrade dependencies with %s: %w", name, err)
+			}
+		}
+	}
+
+	// The repositories may not carry a version that satisfies a constraint
+	before := statuses
+	statuses, err = checkDependencies(deps)
+	if err != nil {
+		return nil, err
+	}
+	var unsatisfied []string
+	for i, s := range statuses {
+		statuses[i].Installed = before[i].Missing() && !s.Missing()
+		if s.Unsatisfied {
+			unsatisfied = append(unsatisfied, fmt.Sprintf("%s %s (wants %s)", s.Name, s.Version, s.Constraint))
+		}
+	}
+	if len(unsatisfied) > 0 {
+		return statuses, fmt.Errorf("installed versions do not satisfy constraints: %s", strings.Join(unsatisfied, ", "))
+	}
+	return statuses, nil
+}
+
+// resolveDependencies maps deps to package entries for this system,
+// reporting every dependency that has no mapping at once
+func resolveDependencies(deps []config.Dependency) ([]string, error) {
+	var system PackageManager
+	var distros []string
+	var entries []string
+	var errs []string
+	for _, dep := range deps {
+		if !dep.HasOverrides() {
+			entries = append(entries, dep.Name)
+			continue
+		}
+		if system == nil {
+			pm, err := detectPackageManager()
+			if err != nil {
+				return nil, fmt.Errorf("cannot resolve %s: %w", dep.Name, err)
+			}
+			system, distros = pm, osDistros()
+		}
+		entry, err := dep.Resolve(system.Name(), distros)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return entries, nil
+}
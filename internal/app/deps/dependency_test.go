@@ -0,0 +1,295 @@
+package deps
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+// plain returns dependencies without package mappings
+func plain(names ...string) []config.Dependency {
+	deps := make([]config.Dependency, len(names))
+	for i, name := range names {
+		deps[i] = config.Dependency{Name: name}
+	}
+	return deps
+}
+
+func TestParseDependency(t *testing.T) {
+	testCases := []struct {
+		entry string
+		want  Dependency
+	}{
+		{"git", Dependency{Package: "git"}},
+		{"flatpak:org.mozilla.firefox", Dependency{Manager: "flatpak", Package: "org.mozilla.firefox"}},
+		{"snap:spotify", Dependency{Manager: "snap", Package: "spotify"}},
+		{"libc6:i386", Dependency{Package: "libc6:i386"}}, // not a manager prefix
+		{"apk:", Dependency{Package: "apk:"}},
+		{"cargo:ripgrep", Dependency{Manager: "cargo", Package: "ripgrep"}},
+		{"pipx:black", Dependency{Manager: "pipx", Package: "black"}},
+		{"go:golang.org/x/tools/gopls@latest", Dependency{Manager: "go", Package: "golang.org/x/tools/gopls@latest"}},
+		{"npm:@biomejs/biome", Dependency{Manager: "npm", Package: "@biomejs/biome"}},
+	}
+
+	for _, tc := range testCases {
+		got := parseDependency(tc.entry)
+		if got != tc.want {
+			t.Errorf("parseDependency(%q) = %+v, want %+v", tc.entry, got, tc.want)
+		}
+		if got.String() != tc.entry {
+			t.Errorf("String() = %q, want %q", got.String(), tc.entry)
+		}
+	}
+}
+
+func TestInstallDependencies(t *testing.T) {
+	var got []string
+	origRun, origLook, origOutput := runCmd, lookPath, outputCmd
+	runCmd = func(env []string, args ...string) error {
+		got = append(got, strings.Join(args, " "))
+		return nil
+	}
+	// Nothing is installed yet
+	outputCmd = func(args ...string) (string, error) {
+		return "", exec.Command("false").Run()
+	}
+	defer func() { runCmd, lookPath, outputCmd = origRun, origLook, origOutput }()
+
+	t.Run("explicit managers", func(t *testing.T) {
+		got = nil
+		lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+		_, err := installDependencies(plain("flatpak:org.mozilla.firefox", "snap:spotify", "flatpak:com.slack.Slack"), "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := []string{
+			"flatpak install --noninteractive --assumeyes org.mozilla.firefox com.slack.Slack",
+			"sudo snap install spotify",
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("Expected commands:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+		}
+	})
+
+	t.Run("nix attribute prefix", func(t *testing.T) {
+		got = nil
+		lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+		if _, err := installDependencies(plain("nix:ripgrep"), ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := "nix-env --install --attr nixpkgs.ripgrep"; len(got) != 1 || got[0] != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("missing manager", func(t *testing.T) {
+		got = nil
+		lookPath = func(file string) (string, error) { return "", fmt.Errorf("not found") }
+
+		_, err := installDependencies(plain("flatpak:org.mozilla.firefox"), "")
+		if err == nil || !strings.Contains(err.Error(), "flatpak is not installed") {
+			t.Errorf("Expected missing manager error, got %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("Expected nothing to run, got %q", got)
+		}
+	})
+}
+
+func TestDetectPackageManagerOrder(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("detection order is tested on linux")
+	}
+	origLook := lookPath
+	defer func() { lookPath = origLook }()
+
+	// Only zypper and flatpak are available; flatpak is never the default
+	lookPath = func(file string) (string, error) {
+		if file == "zypper" || file == "flatpak" {
+			return "/usr/bin/" + file, nil
+		}
+		return "", fmt.Errorf("not found")
+	}
+
+	pm, err := detectPackageManager()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pm.Name() != "zypper" {
+		t.Errorf("Expected zypper, got %s", pm.Name())
+	}
+}
+
+func TestResolveDependencies(t *testing.T) {
+	origLook, origDistros := lookPath, osDistros
+	defer func() { lookPath, osDistros = origLook, origDistros }()
+
+	// An Ubuntu system with apt
+	lookPath = func(file string) (string, error) {
+		if file == "apt-get" {
+			return "/usr/bin/apt-get", nil
+		}
+		return "", fmt.Errorf("not found")
+	}
+	osDistros = func() []string { return []string{"ubuntu", "debian"} }
+
+	fd := config.Dependency{Name: "fd", Managers: map[string]string{"apt": "fd-find", "pacman": "fd"}}
+	pip := config.Dependency{Name: "pip", Distro: map[string]string{"debian": "python3-pip"}, Managers: map[string]string{"apt": "pip"}}
+	bat := config.Dependency{Name: "bat", Default: "bat", Distro: map[string]string{"arch": "bat"}}
+	code := config.Dependency{Name: "code", Managers: map[string]string{"apt": "flatpak:com.visualstudio.code"}}
+
+	t.Run("mapped", func(t *testing.T) {
+		entries, err := resolveDependencies([]config.Dependency{{Name: "git"}, fd, pip, bat, code})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := "git fd-find python3-pip bat flatpak:com.visualstudio.code"
+		if got := strings.Join(entries, " "); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("no mapping", func(t *testing.T) {
+		rg := config.Dependency{Name: "rg", Managers: map[string]string{"pacman": "ripgrep"}}
+		_, err := resolveDependencies([]config.Dependency{fd, rg})
+		if err == nil {
+			t.Fatal("Expected an error for a dependency without a mapping")
+		}
+		for _, want := range []string{"no package mapping for rg on apt (ubuntu, debian)", "pacman"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error to contain %q, got %q", want, err)
+			}
+		}
+	})
+}
+
+func TestSyncDependencies(t *testing.T) {
+	fd := config.Dependency{Name: "fd", Managers: map[string]string{"apt": "fd-find"}}
+	current := []config.Dependency{{Name: "git"}, fd}
+
+	got := syncDependencies(current, []string{"fd", "vim"})
+	if len(got) != 2 || !got[0].HasOverrides() || got[0].Managers["apt"] != "fd-find" || got[1].Name != "vim" {
+		t.Errorf("Expected fd mapping to be kept and vim added, got %+v", got)
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses pacman as the system package manager")
+	}
+	var ran []string
+	var out bytes.Buffer
+	origRun, origLook, origOutput, origStdout := runCmd, lookPath, outputCmd, Stdout
+	runCmd = func(env []string, args ...string) error {
+		ran = append(ran, strings.Join(args, " "))
+		return nil
+	}
+	lookPath = func(file string) (string, error) {
+		if file == "pacman" || file == "cargo" {
+			return "/usr/bin/" + file, nil
+		}
+		return "", fmt.Errorf("not found")
+	}
+	// git and ripgrep are installed
+	outputCmd = func(args ...string) (string, error) {
+		switch strings.Join(args, " ") {
+		case "pacman -Q git":
+			return "git 2.44.0-1\n", nil
+		case "cargo install --list":
+			return "ripgrep v14.1.0:\n    rg\n", nil
+		}
+		return "", exec.Command("false").Run()
+	}
+	Stdout = &out
+	defer func() { runCmd, lookPath, outputCmd, Stdout = origRun, origLook, origOutput, origStdout }()
+
+	deps := plain("git", "vim", "cargo:ripgrep", "cargo:bat", "flatpak:org.mozilla.firefox")
+
+	t.Run("check", func(t *testing.T) {
+		out.Reset()
+		err := checkCmd.Do(checkCmd, "git vim cargo:ripgrep cargo:bat", "flatpak:org.mozilla.firefox")
+		if err == nil || err.Error() != "3 of 5 dependencies missing" {
+			t.Errorf("Expected missing dependencies error, got %v", err)
+		}
+		for _, want := range []string{
+			"✓ git", "2.44.0-1 (pacman)",
+			"✗ vim", "missing (pacman)",
+			"✓ cargo:ripgrep", "14.1.0 (cargo)",
+			"✗ cargo:bat",
+			"? flatpak:org.mozilla.firefox", "flatpak is not installed",
+		} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q:\n%s", want, out.String())
+			}
+		}
+		if len(ran) != 0 {
+			t.Errorf("check must not install anything, ran %q", ran)
+		}
+	})
+
+	t.Run("all installed", func(t *testing.T) {
+		out.Reset()
+		if err := checkCmd.Do(checkCmd, "git", "cargo:ripgrep"); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(out.String(), "All 2 dependencies installed") {
+			t.Errorf("Expected all installed message:\n%s", out.String())
+		}
+	})
+
+	t.Run("missing packages", func(t *testing.T) {
+		missing, err := MissingPackages([]string{"git", "vim", "cargo:ripgrep>=15", "flatpak:org.mozilla.firefox"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := "vim cargo:ripgrep>=15 flatpak:org.mozilla.firefox"
+		if got := strings.Join(missing, " "); got != want {
+			t.Errorf("Expected %q missing, got %q", want, got)
+		}
+	})
+
+	t.Run("install only missing", func(t *testing.T) {
+		ran = nil
+		out.Reset()
+		if _, err := installDependencies(deps[:4], ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := []string{"sudo pacman -S --noconfirm vim", "cargo install --locked bat"}
+		if strings.Join(ran, "\n") != strings.Join(want, "\n") {
+			t.Errorf("Expected commands %q, got %q", want, ran)
+		}
+		if !strings.Contains(out.String(), "2 of 4 dependencies already installed") {
+			t.Errorf("Expected skipped count:\n%s", out.String())
+		}
+	})
+
+	t.Run("nothing missing", func(t *testing.T) {
+		ran = nil
+		out.Reset()
+		if _, err := installDependencies(plain("git"), ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(ran) != 0 || !strings.Contains(out.String(), "Nothing to install") {
+			t.Errorf("Expected nothing to install, ran %q:\n%s", ran, out.String())
+		}
+	})
+}
+
+func TestConfigManagers(t *testing.T) {
+	// Dependencies in arara.yaml may map exactly the supported managers
+	if len(config.PackageManagers) != len(packageManagers) {
+		t.Errorf("config.PackageManagers has %d entries, packageManagers %d", len(config.PackageManagers), len(packageManagers))
+	}
+	for _, name := range config.PackageManagers {
+		if _, ok := packageManagers[name]; !ok {
+			t.Errorf("config.PackageManagers lists %s, which is not a package manager", name)
+		}
+	}
+}
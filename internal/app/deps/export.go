@@ -0,0 +1,79 @@
+package deps
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+// exportFormats lists the formats 'deps export' can write
+var exportFormats = []string{"brewfile", "list"}
+
+// exportDependencies writes the dependencies of groups to w in format
+func exportDependencies(w io.Writer, format string, groups []depGroup) error {
+	switch format {
+	case "brewfile":
+		return exportBrewfile(w, groups)
+	case "list":
+		return exportList(w, groups)
+	}
+	return fmt.Errorf("unknown export format: %s (supported: %s)", format, strings.Join(exportFormats, ", "))
+}
+
+// exportBrewfile writes a Brewfile for 'brew bundle'. Package names are
+// resolved for brew on macOS and cask: dependencies are written as casks;
+// dependencies bound to another package manager or without a brew mapping
+// are noted as comments.
+func exportBrewfile(w io.Writer, groups []depGroup) error {
+	fmt.Fprintln(w, "# Exported by 'arara deps export brewfile'")
+	seen := make(map[string]bool)
+	for _, g := range groups {
+		if len(g.Dependencies) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n# %s\n", g.Name)
+		for _, dep := range g.Dependencies {
+			entry, err := dep.Resolve("brew", []string{"darwin", "macos"})
+			if err != nil {
+				fmt.Fprintf(w, "# skipped %s: no brew mapping\n", dep.Name)
+				continue
+			}
+			entry, _ = config.SplitConstraint(entry)
+			d := parseDependency(entry)
+			kind := "brew"
+			switch d.Manager {
+			case "", "brew":
+			case "cask":
+				kind = "cask"
+			default:
+				fmt.Fprintf(w, "# skipped %s: installed with %s\n", dep.Name, d.Manager)
+				continue
+			}
+			if seen[kind+" "+d.Package] {
+				continue
+			}
+			seen[kind+" "+d.Package] = true
+			fmt.Fprintf(w, "%s %q\n", kind, d.Package)
+		}
+	}
+	return nil
+}
+
+// exportList writes the dependencies as a plain list, one per line with
+// the group names as comments, in the format read by 'deps sync'
+func exportList(w io.Writer, groups []depGroup) error {
+	fmt.Fprintln(w, "# Exported by 'arara deps export list'")
+	for _, g := range groups {
+		if len(g.Dependencies) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n## %s\n", g.Name)
+		for _, dep := range g.Dependencies {
+			// Mappings can't be expressed in a list; keep the logical name
+			fmt.Fprintln(w, dep.Name+dep.Version)
+		}
+	}
+	return nil
+}
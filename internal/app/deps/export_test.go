@@ -0,0 +1,72 @@
+package deps
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+func TestExportDependencies(t *testing.T) {
+	groups := []depGroup{
+		{Name: "core", DependencyGroup: config.DependencyGroup{Dependencies: []config.Dependency{
+			{Name: "git"},
+			{Name: "fd", Managers: map[string]string{"apt": "fd-find"}},
+			{Name: "neovim", Version: ">=0.9"},
+			{Name: "ripgrep", Managers: map[string]string{"brew": "cargo:ripgrep"}},
+			{Name: "cask:firefox"},
+		}}},
+		{Name: "gui", DependencyGroup: config.DependencyGroup{Dependencies: []config.Dependency{
+			{Name: "xclip", Distro: map[string]string{"arch": "xclip"}},
+			{Name: "git"},
+		}}},
+		{Name: "empty"},
+	}
+
+	testCases := []struct {
+		format string
+		want   string
+	}{
+		{"brewfile", `# Exported by 'arara deps export brewfile'
+
+# core
+brew "git"
+# skipped fd: no brew mapping
+brew "neovim"
+# skipped ripgrep: installed with cargo
+cask "firefox"
+
+# gui
+# skipped xclip: no brew mapping
+`},
+		{"list", `# Exported by 'arara deps export list'
+
+## core
+git
+fd
+neovim>=0.9
+ripgrep
+cask:firefox
+
+## gui
+xclip
+git
+`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := exportDependencies(&buf, tc.format, groups); err != nil {
+				t.Fatalf("exportDependencies: %v", err)
+			}
+			if buf.String() != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", buf.String(), tc.want)
+			}
+		})
+	}
+
+	if err := exportDependencies(&bytes.Buffer{}, "yaml", groups); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,118 @@
+package deps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// goManager installs Go programs with "go install". Go keeps no record of
+// what it installed, so packages are detected by their binary in GOBIN
+// and versions are read from the module info embedded in the binary.
+type goManager struct {
+	cliManager
+}
+
+// majorVersion matches the /vN suffix of a major version module path
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+func (m *goManager) Install(pkgs ...string) error {
+	versioned := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		versioned[i] = pkg
+		if !strings.Contains(pkg, "@") {
+			versioned[i] += "@latest"
+		}
+	}
+	return m.cliManager.Install(versioned...)
+}
+
+func (m *goManager) Remove(pkgs ...string) error {
+	for _, pkg := range pkgs {
+		path, err := goBinaryPath(pkg)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func (m *goManager) Update(pkgs ...string) error {
+	if len(pkgs) == 0 {
+		return fmt.Errorf("go cannot upgrade all packages, name them explicitly")
+	}
+	latest := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		path, _, _ := strings.Cut(pkg, "@")
+		latest[i] = path + "@latest"
+	}
+	return m.cliManager.Install(latest...)
+}
+
+func (m *goManager) IsInstalled(pkg string) (bool, error) {
+	version, err := m.InstalledVersion(pkg)
+	return version != "", err
+}
+
+func (m *goManager) InstalledVersion(pkg string) (string, error) {
+	path, err := goBinaryPath(pkg)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", nil
+	}
+
+	out, err := outputCmd("go", "version", "-m", path)
+	if err != nil {
+		return "unknown", nil
+	}
+	// path	golang.org/x/tools/gopls
+	// mod	golang.org/x/tools/gopls	v0.16.1	h1:...
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "mod" {
+			return fields[2], nil
+		}
+	}
+	return "unknown", nil
+}
+
+func (m *goManager) Search(query string) ([]string, error) {
+	return nil, fmt.Errorf("go does not support search")
+}
+
+// goBinaryPath returns where "go install" puts the binary for pkg
+func goBinaryPath(pkg string) (string, error) {
+	out, err := outputCmd("go", "env", "GOBIN", "GOPATH")
+	if err != nil {
+		return "", fmt.Errorf("failed to read go env: %w", err)
+	}
+	lines := strings.Split(out, "\n")
+	dir := strings.TrimSpace(lines[0])
+	if dir == "" && len(lines) > 1 {
+		gopath := strings.TrimSpace(lines[1])
+		if gopath == "" {
+			return "", fmt.Errorf("neither GOBIN nor GOPATH is set")
+		}
+		dir = filepath.Join(filepath.SplitList(gopath)[0], "bin")
+	}
+	return filepath.Join(dir, goBinaryName(pkg)), nil
+}
+
+// goBinaryName returns the name of the binary built from the package path
+// pkg, which may carry an @version suffix
+func goBinaryName(pkg string) string {
+	path, _, _ := strings.Cut(pkg, "@")
+	elems := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	name := elems[len(elems)-1]
+	if majorVersion.MatchString(name) && len(elems) > 1 {
+		name = elems[len(elems)-2]
+	}
+	return name
+}
@@ -0,0 +1,80 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoBinaryName(t *testing.T) {
+	testCases := map[string]string{
+		"golang.org/x/tools/gopls@latest":          "gopls",
+		"github.com/junegunn/fzf":                  "fzf",
+		"github.com/jesseduffield/lazygit@v0.40.2": "lazygit",
+		"github.com/rwxrob/bonzai/v2/cmd/z":        "z",
+		"example.com/tool/v2@latest":               "tool",
+	}
+	for pkg, want := range testCases {
+		if got := goBinaryName(pkg); got != want {
+			t.Errorf("goBinaryName(%q) = %q, want %q", pkg, got, want)
+		}
+	}
+}
+
+func TestGoManager(t *testing.T) {
+	gobin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(gobin, "gopls"), []byte("binary"), 0755); err != nil {
+		t.Fatalf("Failed to write fake binary: %v", err)
+	}
+
+	var ran []string
+	origRun, origOutput := runCmd, outputCmd
+	runCmd = func(env []string, args ...string) error {
+		ran = append(ran, strings.Join(args, " "))
+		return nil
+	}
+	outputCmd = func(args ...string) (string, error) {
+		switch strings.Join(args[:2], " ") {
+		case "go env":
+			return gobin + "\n/home/user/go\n", nil
+		case "go version":
+			return args[3] + ": go1.22.0\n\tpath\tgolang.org/x/tools/gopls\n\tmod\tgolang.org/x/tools/gopls\tv0.16.1\th1:abc=\n", nil
+		}
+		t.Fatalf("Unexpected command: %q", args)
+		return "", nil
+	}
+	defer func() { runCmd, outputCmd = origRun, origOutput }()
+
+	pm := packageManagers["go"]
+
+	version, err := pm.InstalledVersion("golang.org/x/tools/gopls@latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if version != "v0.16.1" {
+		t.Errorf("Expected version v0.16.1, got %q", version)
+	}
+
+	installed, err := pm.IsInstalled("github.com/junegunn/fzf")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if installed {
+		t.Error("Expected fzf not to be installed")
+	}
+
+	if err := pm.Install("github.com/junegunn/fzf", "golang.org/x/tools/gopls@v0.15.0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := "go install github.com/junegunn/fzf@latest golang.org/x/tools/gopls@v0.15.0"; len(ran) != 1 || ran[0] != want {
+		t.Errorf("Expected %q, got %q", want, ran)
+	}
+
+	if err := pm.Remove("golang.org/x/tools/gopls"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(gobin, "gopls")); !os.IsNotExist(err) {
+		t.Error("Expected gopls binary to be removed")
+	}
+}
@@ -0,0 +1,171 @@
+package deps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BuddhiLW/arara/internal/app/compat"
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+// depGroup is a named group of dependencies with its compatibility state
+type depGroup struct {
+	Name string
+	config.DependencyGroup
+	Failures []compat.Failure // why the group doesn't apply to this system
+}
+
+// configGroups returns the core group followed by the named groups of cfg
+// sorted by name. A "core" entry in dependency_groups extends the
+// top-level dependencies.
+func configGroups(cfg *config.DotfilesConfig) []depGroup {
+	core := depGroup{Name: config.CoreGroup}
+	if g, ok := cfg.DependencyGroups[config.CoreGroup]; ok {
+		core.DependencyGroup = g
+	}
+	core.Dependencies = flattenDependencies(append(append([]config.Dependency{}, cfg.Dependencies...), core.Dependencies...))
+	core.Failures = compat.Failures(compat.FromConfig(core.Compat))
+
+	var names []string
+	for name := range cfg.DependencyGroups {
+		if name != config.CoreGroup {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	groups := []depGroup{core}
+	for _, name := range names {
+		g := depGroup{Name: name, DependencyGroup: cfg.DependencyGroups[name]}
+		g.Dependencies = flattenDependencies(g.Dependencies)
+		g.Failures = compat.Failures(compat.FromConfig(g.Compat))
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// selectDependencies returns the dependencies of the groups named in
+// names or, if none are named, of every group that is not optional.
+// Incompatible groups are skipped with a notice, or rejected when named.
+func selectDependencies(groups []depGroup, names []string) ([]config.Dependency, error) {
+	byName := make(map[string]depGroup)
+	for _, g := range groups {
+		byName[g.Name] = g
+	}
+
+	var selected []depGroup
+	if len(names) == 0 {
+		for _, g := range groups {
+			if g.Optional {
+				continue
+			}
+			if len(g.Failures) > 0 {
+				fmt.Fprintf(Stdout, "Skipping group %s (%s)\n", g.Name, g.Failures[0])
+				continue
+			}
+			selected = append(selected, g)
+		}
+	} else {
+		for _, name := range names {
+			g, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown dependency group: %s", name)
+			}
+			if len(g.Failures) > 0 {
+				return nil, fmt.Errorf("dependency group %s is not compatible with this system (%s)", name, g.Failures[0])
+			}
+			selected = append(selected, g)
+		}
+	}
+
+	// A dependency listed in several groups is only installed once
+	seen := make(map[string]bool)
+	var deps []config.Dependency
+	for _, g := range selected {
+		for _, dep := range g.Dependencies {
+			if seen[dep.Name] {
+				continue
+			}
+			seen[dep.Name] = true
+			deps = append(deps, dep)
+		}
+	}
+	return deps, nil
+}
+
+// declaredNames returns the names of the dependencies of every group,
+// including optional and incompatible ones
+func declaredNames(groups []depGroup) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, g := range groups {
+		for _, dep := range g.Dependencies {
+			if !seen[dep.Name] {
+				seen[dep.Name] = true
+				names = append(names, dep.Name)
+			}
+		}
+	}
+	return names
+}
+
+// flattenDependencies splits plain entries holding several packages
+// ("git vim tmux") into one dependency per package
+func flattenDependencies(deps []config.Dependency) []config.Dependency {
+	var flatDeps []config.Dependency
+	for _, dep := range deps {
+		if dep.HasOverrides() {
+			flatDeps = append(flatDeps, dep)
+			continue
+		}
+		for _, singleDep := range strings.Fields(dep.String()) {
+			flatDeps = append(flatDeps, config.ParseDependency(singleDep))
+		}
+	}
+	return flatDeps
+}
+
+// parseGroupFlags splits the --group (-g) flags off args. Each flag takes
+// one group name or a comma separated list and may be repeated.
+func parseGroupFlags(args []string) (groups, rest []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var value string
+		switch {
+		case arg == "--group" || arg == "-g":
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("%s requires a group name", arg)
+			}
+			i++
+			value = args[i]
+		case strings.HasPrefix(arg, "--group="):
+			value = strings.TrimPrefix(arg, "--group=")
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				groups = append(groups, name)
+			}
+		}
+	}
+	return groups, rest, nil
+}
+
+// groupFlag returns the single group named by the --group flags in args,
+// defaulting to the core group
+func groupFlag(args []string) (group string, rest []string, err error) {
+	groups, rest, err := parseGroupFlags(args)
+	if err != nil {
+		return "", nil, err
+	}
+	switch len(groups) {
+	case 0:
+		return config.CoreGroup, rest, nil
+	case 1:
+		return groups[0], rest, nil
+	}
+	return "", nil, fmt.Errorf("only one --group may be given")
+}
@@ -0,0 +1,133 @@
+package deps
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+const groupsYAML = `
+dependencies:
+  - git
+  - curl wget
+dependency_groups:
+  gui:
+    description: Desktop applications
+    optional: true
+    dependencies:
+      - flatpak:org.mozilla.firefox
+  dev:
+    dependencies:
+      - cargo:ripgrep
+      - git
+  fonts:
+    compat:
+      os: nonexistent-os
+    dependencies:
+      - ttf-jetbrains-mono
+`
+
+func testGroups(t *testing.T) []depGroup {
+	t.Helper()
+	var cfg config.DotfilesConfig
+	if err := yaml.Unmarshal([]byte(groupsYAML), &cfg); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	return configGroups(&cfg)
+}
+
+func names(deps []config.Dependency) string {
+	var out []string
+	for _, dep := range deps {
+		out = append(out, dep.Name)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestConfigGroups(t *testing.T) {
+	groups := testGroups(t)
+
+	var got []string
+	for _, g := range groups {
+		got = append(got, g.Name+"="+names(g.Dependencies))
+	}
+	want := "core=git curl wget dev=cargo:ripgrep git fonts=ttf-jetbrains-mono gui=flatpak:org.mozilla.firefox"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Expected groups %q, got %q", want, strings.Join(got, " "))
+	}
+	if len(groups[2].Failures) == 0 {
+		t.Error("Expected fonts group to be incompatible")
+	}
+	if !groups[3].Optional || groups[3].Description != "Desktop applications" {
+		t.Errorf("Expected optional gui group with description, got %+v", groups[3])
+	}
+}
+
+func TestSelectDependencies(t *testing.T) {
+	var out bytes.Buffer
+	origStdout := Stdout
+	Stdout = &out
+	defer func() { Stdout = origStdout }()
+
+	groups := testGroups(t)
+
+	testCases := []struct {
+		name    string
+		groups  []string
+		want    string
+		wantErr string
+	}{
+		{"default skips optional and incompatible", nil, "git curl wget cargo:ripgrep", ""},
+		{"named optional group", []string{"gui"}, "flatpak:org.mozilla.firefox", ""},
+		{"several groups", []string{"dev", "core"}, "cargo:ripgrep git curl wget", ""},
+		{"incompatible group", []string{"fonts"}, "", "not compatible"},
+		{"unknown group", []string{"games"}, "", "unknown dependency group: games"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deps, err := selectDependencies(groups, tc.groups)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := names(deps); got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	if !strings.Contains(out.String(), "Skipping group fonts") {
+		t.Errorf("Expected notice for skipped group:\n%s", out.String())
+	}
+}
+
+func TestParseGroupFlags(t *testing.T) {
+	groups, rest, err := parseGroupFlags([]string{"-g", "gui", "--group=dev,fonts", "git", "--group", "core"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(groups, " ") != "gui dev fonts core" || strings.Join(rest, " ") != "git" {
+		t.Errorf("Unexpected groups %q and rest %q", groups, rest)
+	}
+
+	if _, _, err := parseGroupFlags([]string{"--group"}); err == nil {
+		t.Error("Expected error for --group without a name")
+	}
+
+	if _, _, err := groupFlag([]string{"-g", "gui,dev", "git"}); err == nil {
+		t.Error("Expected error for more than one group")
+	}
+	group, rest, err := groupFlag([]string{"git"})
+	if err != nil || group != config.CoreGroup || len(rest) != 1 {
+		t.Errorf("Expected core group by default, got %q %q %v", group, rest, err)
+	}
+}
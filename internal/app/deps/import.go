@@ -0,0 +1,113 @@
+package deps
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// importSources lists the sources 'deps import' can read from
+var importSources = []string{"pacman", "apt", "dnf", "brew", "brewfile"}
+
+// rpmNEVRA matches name-version-release.arch as printed by dnf
+var rpmNEVRA = regexp.MustCompile(`^(.+)-[^-]+-[^-]+\.[^.]+$`)
+
+// importPackages returns the explicitly installed packages reported by
+// source. The brewfile source reads the Brewfile at path instead of
+// querying the system. Entries that cannot be imported are returned as
+// skipped.
+func importPackages(source, path string) (pkgs, skipped []string, err error) {
+	switch source {
+	case "pacman":
+		// Explicitly installed, by name only
+		pkgs, err = importLines("pacman", "-Qqe")
+	case "apt":
+		pkgs, err = importLines("apt-mark", "showmanual")
+	case "dnf":
+		var lines []string
+		lines, err = importLines("dnf", "history", "userinstalled")
+		for _, line := range lines {
+			// Skip the header and anything that isn't a package
+			if m := rpmNEVRA.FindStringSubmatch(line); m != nil {
+				pkgs = append(pkgs, m[1])
+			}
+		}
+	case "brew":
+		pkgs, err = importLines("brew", "leaves", "--installed-on-request")
+		if err == nil {
+			var casks []string
+			casks, err = importLines("brew", "list", "--cask", "-1")
+			for _, cask := range casks {
+				pkgs = append(pkgs, "cask:"+cask)
+			}
+		}
+	case "brewfile":
+		if path == "" {
+			path = "Brewfile"
+		}
+		return readBrewfile(path)
+	default:
+		return nil, nil, fmt.Errorf("unknown import source: %s (supported: %s)", source, strings.Join(importSources, ", "))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to import from %s: %w", source, err)
+	}
+
+	sort.Strings(pkgs)
+	return pkgs, nil, nil
+}
+
+// importLines runs a command and returns its non-empty output lines
+func importLines(args ...string) ([]string, error) {
+	out, err := outputCmd(args...)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// readBrewfile returns the formulae and casks of the Brewfile at path,
+// casks prefixed with cask: so they are installed as such. Taps, Mac App
+// Store apps and other entries are returned as skipped.
+func readBrewfile(path string) (pkgs, skipped []string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read Brewfile: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// brew "neovim", args: ["HEAD"]
+		kind, rest, _ := strings.Cut(line, " ")
+		name, _, _ := strings.Cut(strings.TrimSpace(rest), ",")
+		name = strings.Trim(strings.TrimSpace(name), `"'`)
+
+		switch kind {
+		case "brew":
+			pkgs = append(pkgs, name)
+		case "cask":
+			pkgs = append(pkgs, "cask:"+name)
+		default:
+			skipped = append(skipped, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read Brewfile: %w", err)
+	}
+	return pkgs, skipped, nil
+}
@@ -0,0 +1,78 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportPackages(t *testing.T) {
+	outputs := map[string]string{
+		"pacman -Qqe":                        "git\nneovim\n\n",
+		"apt-mark showmanual":                "curl\ngit\n",
+		"dnf history userinstalled":          "Packages installed by user\ngit-2.45.2-1.fc40.x86_64\ntmux-3.4-1.fc40.x86_64\n",
+		"brew leaves --installed-on-request": "ripgrep\nneovim\n",
+		"brew list --cask -1":                "firefox\n",
+	}
+	origOutput := outputCmd
+	outputCmd = func(args ...string) (string, error) {
+		return outputs[strings.Join(args, " ")], nil
+	}
+	defer func() { outputCmd = origOutput }()
+
+	testCases := []struct {
+		source string
+		want   []string
+	}{
+		{"pacman", []string{"git", "neovim"}},
+		{"apt", []string{"curl", "git"}},
+		{"dnf", []string{"git", "tmux"}},
+		{"brew", []string{"cask:firefox", "neovim", "ripgrep"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.source, func(t *testing.T) {
+			got, skipped, err := importPackages(tc.source, "")
+			if err != nil {
+				t.Fatalf("importPackages: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+			if len(skipped) != 0 {
+				t.Errorf("skipped %v", skipped)
+			}
+		})
+	}
+
+	if _, _, err := importPackages("portage", ""); err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
+
+func TestReadBrewfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Brewfile")
+	brewfile := `# Packages
+tap "homebrew/cask-fonts"
+brew "git"
+brew "neovim", args: ["HEAD"]
+cask 'firefox'
+mas "Xcode", id: 497799835
+`
+	if err := os.WriteFile(path, []byte(brewfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, skipped, err := importPackages("brewfile", path)
+	if err != nil {
+		t.Fatalf("importPackages: %v", err)
+	}
+	if want := []string{"git", "neovim", "cask:firefox"}; !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("packages = %v, want %v", pkgs, want)
+	}
+	if len(skipped) != 2 || !strings.HasPrefix(skipped[0], "tap") || !strings.HasPrefix(skipped[1], "mas") {
+		t.Errorf("skipped = %v", skipped)
+	}
+}
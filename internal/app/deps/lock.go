@@ -0,0 +1,179 @@
+package deps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile is the name of the lockfile written next to arara.yaml
+const LockFile = "arara.lock"
+
+const lockHeader = "# Generated by 'arara deps install'. Do not edit.\n"
+
+// Lock records the package versions installed for the dependencies of a
+// namespace
+type Lock struct {
+	Packages []LockedPackage `yaml:"packages"`
+}
+
+// LockedPackage is a dependency as it was installed
+type LockedPackage struct {
+	Name    string `yaml:"name"` // logical name from arara.yaml
+	Manager string `yaml:"manager"`
+	Package string `yaml:"package"`
+	Version string `yaml:"version"`
+}
+
+// lockPath returns the path of the active namespace's lockfile
+func lockPath() (string, error) {
+	dotfilesPath, err := config.GetDotfilesPath()
+	if err != nil {
+		return "", fmt.Errorf("failed to get dotfiles path: %w", err)
+	}
+	if dotfilesPath == "" {
+		return "", fmt.Errorf("no dotfiles path found for active namespace")
+	}
+	return filepath.Join(dotfilesPath, LockFile), nil
+}
+
+// newLock records the installed dependencies in statuses
+func newLock(statuses []depStatus) *Lock {
+	lock := &Lock{}
+	for _, s := range statuses {
+		if s.Manager == nil || s.Missing() {
+			continue
+		}
+		lock.Packages = append(lock.Packages, LockedPackage{
+			Name:    s.Name,
+			Manager: s.Manager.Name(),
+			Package: s.Package,
+			Version: s.Version,
+		})
+	}
+	sort.Slice(lock.Packages, func(i, j int) bool {
+		return lock.Packages[i].Name < lock.Packages[j].Name
+	})
+	return lock
+}
+
+// mergeLock returns old updated with the packages of lock, keeping the
+// packages of groups that were not installed this time
+func mergeLock(old, lock *Lock) *Lock {
+	merged := &Lock{}
+	updated := make(map[string]bool)
+	for _, pkg := range lock.Packages {
+		updated[pkg.Name] = true
+	}
+	for _, pkg := range old.Packages {
+		if !updated[pkg.Name] {
+			merged.Packages = append(merged.Packages, pkg)
+		}
+	}
+	merged.Packages = append(merged.Packages, lock.Packages...)
+	sort.Slice(merged.Packages, func(i, j int) bool {
+		return merged.Packages[i].Name < merged.Packages[j].Name
+	})
+	return merged
+}
+
+// writeLock writes lock to path
+func writeLock(path string, lock *Lock) error {
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+	if err := os.WriteFile(path, append([]byte(lockHeader), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+// readLock reads the lockfile at path
+func readLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no %s found, run 'arara deps install' first", LockFile)
+		}
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	var lock Lock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile: %w", err)
+	}
+	return &lock, nil
+}
+
+// lockDiff is a deviation of the machine from the lockfile
+type lockDiff struct {
+	Kind    string // changed, missing, unlocked or stale
+	Name    string
+	Manager string
+	Locked  string // version in the lockfile
+	Current string // version installed now
+}
+
+func (d lockDiff) String() string {
+	switch d.Kind {
+	case "changed":
+		return fmt.Sprintf("%s → %s (%s)", d.Locked, d.Current, d.Manager)
+	case "missing":
+		return fmt.Sprintf("%s not installed (%s)", d.Locked, d.Manager)
+	case "unlocked":
+		return fmt.Sprintf("declared but not in %s", LockFile)
+	default:
+		return fmt.Sprintf("in %s but no longer declared", LockFile)
+	}
+}
+
+// diffLock compares lock to the installed versions of its packages and
+// to the declared dependency names
+func diffLock(lock *Lock, declared []string) ([]lockDiff, error) {
+	var diffs []lockDiff
+
+	isDeclared := make(map[string]bool)
+	for _, name := range declared {
+		isDeclared[name] = true
+	}
+	locked := make(map[string]bool)
+
+	for _, pkg := range lock.Packages {
+		locked[pkg.Name] = true
+		if !isDeclared[pkg.Name] {
+			diffs = append(diffs, lockDiff{Kind: "stale", Name: pkg.Name, Manager: pkg.Manager})
+			continue
+		}
+
+		pm, err := lookupManager(pkg.Manager)
+		if err != nil {
+			return nil, err
+		}
+		current, err := pm.InstalledVersion(pkg.Package)
+		if err != nil {
+			return nil, err
+		}
+
+		d := lockDiff{Name: pkg.Name, Manager: pkg.Manager, Locked: pkg.Version, Current: current}
+		switch {
+		case current == "":
+			d.Kind = "missing"
+		case current != pkg.Version:
+			d.Kind = "changed"
+		default:
+			continue
+		}
+		diffs = append(diffs, d)
+	}
+
+	for _, name := range declared {
+		if !locked[name] {
+			diffs = append(diffs, lockDiff{Kind: "unlocked", Name: name})
+		}
+	}
+	return diffs, nil
+}
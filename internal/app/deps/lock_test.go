@@ -0,0 +1,142 @@
+package deps
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+func TestLockRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFile)
+	statuses := []depStatus{
+		{Name: "neovim", Dependency: Dependency{Package: "neovim"}, Manager: packageManagers["pacman"], Version: "0.10.1-1"},
+		{Name: "fd", Dependency: Dependency{Package: "fd"}, Manager: packageManagers["pacman"], Version: "10.1.0-1"},
+		{Name: "missing", Dependency: Dependency{Package: "missing"}, Manager: packageManagers["pacman"]},
+	}
+
+	if err := writeLock(path, newLock(statuses)); err != nil {
+		t.Fatalf("writeLock() error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), lockHeader) {
+		t.Errorf("Expected lockfile header, got:\n%s", data)
+	}
+
+	lock, err := readLock(path)
+	if err != nil {
+		t.Fatalf("readLock() error = %v", err)
+	}
+	want := []LockedPackage{
+		{Name: "fd", Manager: "pacman", Package: "fd", Version: "10.1.0-1"},
+		{Name: "neovim", Manager: "pacman", Package: "neovim", Version: "0.10.1-1"},
+	}
+	if fmt.Sprint(lock.Packages) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, lock.Packages)
+	}
+
+	if _, err := readLock(filepath.Join(t.TempDir(), LockFile)); err == nil || !strings.Contains(err.Error(), "deps install") {
+		t.Errorf("Expected hint to run deps install, got %v", err)
+	}
+}
+
+func TestDiffLock(t *testing.T) {
+	origLook, origOutput := lookPath, outputCmd
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	outputCmd = func(args ...string) (string, error) {
+		switch strings.Join(args, " ") {
+		case "pacman -Q neovim":
+			return "neovim 0.10.1-1\n", nil
+		case "pacman -Q fd":
+			return "fd 10.1.0-1\n", nil
+		}
+		return "", exec.Command("false").Run()
+	}
+	defer func() { lookPath, outputCmd = origLook, origOutput }()
+
+	lock := &Lock{Packages: []LockedPackage{
+		{Name: "fd", Manager: "pacman", Package: "fd", Version: "10.1.0-1"},
+		{Name: "neovim", Manager: "pacman", Package: "neovim", Version: "0.9.5-1"},
+		{Name: "ripgrep", Manager: "pacman", Package: "ripgrep", Version: "14.1.0-1"},
+		{Name: "old", Manager: "pacman", Package: "old", Version: "1.0"},
+	}}
+
+	diffs, err := diffLock(lock, []string{"fd", "neovim", "ripgrep", "bat"})
+	if err != nil {
+		t.Fatalf("diffLock() error = %v", err)
+	}
+
+	var got []string
+	for _, d := range diffs {
+		got = append(got, d.Kind+" "+d.Name+": "+d.String())
+	}
+	want := []string{
+		"changed neovim: 0.9.5-1 → 0.10.1-1 (pacman)",
+		"missing ripgrep: 14.1.0-1 not installed (pacman)",
+		"stale old: in arara.lock but no longer declared",
+		"unlocked bat: declared but not in arara.lock",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected diffs:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestInstallDependencies_Constraints(t *testing.T) {
+	var ran []string
+	origRun, origLook, origOutput := runCmd, lookPath, outputCmd
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	version := "0.8.3"
+	runCmd = func(env []string, args ...string) error {
+		ran = append(ran, strings.Join(args, " "))
+		version = "0.10.1"
+		return nil
+	}
+	outputCmd = func(args ...string) (string, error) {
+		return "neovim " + version + "\n", nil
+	}
+	defer func() { runCmd, lookPath, outputCmd = origRun, origLook, origOutput }()
+
+	// An outdated install is upgraded to satisfy the constraint
+	statuses, err := installDependencies([]config.Dependency{config.ParseDependency("brew:neovim>=0.9")}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ran) != 1 || ran[0] != "brew upgrade neovim" {
+		t.Errorf("Expected brew upgrade, got %q", ran)
+	}
+	if !statuses[0].OK() || statuses[0].Version != "0.10.1" {
+		t.Errorf("Expected satisfied status after upgrade, got %+v", statuses[0])
+	}
+
+	// The upgraded version overshoots an upper bound
+	ran = nil
+	version = "0.8.3"
+	_, err = installDependencies([]config.Dependency{config.ParseDependency("brew:neovim>=0.9,<0.10")}, "")
+	if err == nil || !strings.Contains(err.Error(), "neovim 0.10.1 (wants >=0.9,<0.10)") {
+		t.Errorf("Expected unsatisfied constraint error, got %v", err)
+	}
+}
+
+func TestMergeLock(t *testing.T) {
+	old := &Lock{Packages: []LockedPackage{
+		{Name: "firefox", Manager: "flatpak", Package: "org.mozilla.firefox", Version: "131.0"},
+		{Name: "git", Manager: "pacman", Package: "git", Version: "2.43.0-1"},
+	}}
+	lock := &Lock{Packages: []LockedPackage{
+		{Name: "git", Manager: "pacman", Package: "git", Version: "2.44.0-1"},
+		{Name: "curl", Manager: "pacman", Package: "curl", Version: "8.7.1-1"},
+	}}
+
+	merged := mergeLock(old, lock)
+	var got []string
+	for _, pkg := range merged.Packages {
+		got = append(got, pkg.Name+"@"+pkg.Version)
+	}
+	if want := "curl@8.7.1-1 firefox@131.0 git@2.44.0-1"; strings.Join(got, " ") != want {
+		t.Errorf("Expected %q, got %q", want, strings.Join(got, " "))
+	}
+}
@@ -0,0 +1,16 @@
+package deps
+
+import (
+	"os"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/privilege"
+)
+
+func TestMain(m *testing.M) {
+	// Escalate with sudo without validating credentials
+	privilegeSession = func() (*privilege.Session, error) {
+		return &privilege.Session{Tool: "sudo"}, nil
+	}
+	os.Exit(m.Run())
+}
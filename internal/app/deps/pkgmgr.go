@@ -0,0 +1,640 @@
+package deps
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// PackageManager installs, removes and queries system packages
+type PackageManager interface {
+	// Name returns the package manager name (apt, dnf, pacman, ...)
+	Name() string
+
+	// Install installs pkgs without prompting
+	Install(pkgs ...string) error
+
+	// Remove uninstalls pkgs without prompting
+	Remove(pkgs ...string) error
+
+	// IsInstalled reports whether pkg is installed
+	IsInstalled(pkg string) (bool, error)
+
+	// InstalledVersion returns the installed version of pkg or an empty
+	// string if it is not installed
+	InstalledVersion(pkg string) (string, error)
+
+	// Search returns the names of packages matching query
+	Search(query string) ([]string, error)
+
+	// Update upgrades pkgs, or every installed package if none are given
+	Update(pkgs ...string) error
+}
+
+// runCmd runs a command with output attached to the terminal; replaced in tests
+var runCmd = func(env []string, args ...string) error {
+	fmt.Printf("Running: %s\n", strings.Join(args, " "))
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// outputCmd runs a command and returns its stdout; replaced in tests
+var outputCmd = func(args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &out
+	err := cmd.Run()
+	return out.String(), err
+}
+
+// cliManager implements PackageManager on top of a package manager CLI.
+// Each command is the argument list the packages (or query) are appended to.
+type cliManager struct {
+	name       string
+	binary     string   // executable used for detection
+	privileged bool     // install, remove and update need root
+	env        []string // extra env to avoid interactive prompts
+	attrPrefix string   // prepended to package names on install (nix)
+	listQuery  bool     // query lists every package instead of taking one
+
+	install    []string
+	remove     []string
+	upgrade    []string // upgrade the given packages
+	upgradeAll []string // upgrade every package; nil if unsupported
+	query      []string // version query; non-zero exit means not installed
+	search     []string // nil if unsupported
+
+	parseVersion func(pkg, out string) string
+	parseSearch  func(out string) []string
+
+	// Offline installs (see cache.go); nil if unsupported
+	fetch        func(dir string) []string // download a package and its dependencies into dir
+	installLocal []string                  // install package files
+	packageExts  []string                  // suffixes of package files
+}
+
+func (m *cliManager) Name() string { return m.name }
+
+// executable returns the command that must be on PATH for m to work
+func (m *cliManager) executable() string { return m.binary }
+
+func (m *cliManager) Install(pkgs ...string) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+	if m.attrPrefix != "" {
+		prefixed := make([]string, len(pkgs))
+		for i, pkg := range pkgs {
+			prefixed[i] = m.attrPrefix + pkg
+		}
+		pkgs = prefixed
+	}
+	return m.run(m.install, pkgs...)
+}
+
+func (m *cliManager) Remove(pkgs ...string) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+	return m.run(m.remove, pkgs...)
+}
+
+func (m *cliManager) Update(pkgs ...string) error {
+	if len(pkgs) == 0 {
+		if m.upgradeAll == nil {
+			return fmt.Errorf("%s cannot upgrade all packages, name them explicitly", m.name)
+		}
+		return m.run(m.upgradeAll)
+	}
+	return m.run(m.upgrade, pkgs...)
+}
+
+func (m *cliManager) IsInstalled(pkg string) (bool, error) {
+	version, err := m.InstalledVersion(pkg)
+	return version != "", err
+}
+
+func (m *cliManager) InstalledVersion(pkg string) (string, error) {
+	args := clone(m.query)
+	if !m.listQuery {
+		args = append(args, pkg)
+	}
+	out, err := outputCmd(args...)
+	if err != nil {
+		// Query tools exit non-zero for unknown packages
+		if _, ok := err.(*exec.ExitError); ok {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to query %s: %w", pkg, err)
+	}
+	return m.parseVersion(pkg, out), nil
+}
+
+func (m *cliManager) Search(query string) ([]string, error) {
+	if m.search == nil {
+		return nil, fmt.Errorf("%s does not support search", m.name)
+	}
+	out, err := outputCmd(append(clone(m.search), query)...)
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, nil // no matches
+		}
+		return nil, fmt.Errorf("failed to search %s: %w", query, err)
+	}
+	return m.parseSearch(out), nil
+}
+
+// run executes base with args appended, escalating when needed
+func (m *cliManager) run(base []string, args ...string) error {
+	cmdArgs := append(clone(base), args...)
+	if m.privileged {
+		var err error
+		if cmdArgs, err = escalate(cmdArgs); err != nil {
+			return err
+		}
+	}
+	return runCmd(m.env, cmdArgs...)
+}
+
+// clone returns a copy of args that is safe to append to
+func clone(args []string) []string {
+	return append([]string{}, args...)
+}
+
+// lastField returns the last whitespace separated field of the first line
+func lastField(_ string, out string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
+
+// firstFields returns the first field of every non-empty line that does
+// not start with one of the skip prefixes
+func firstFields(out string, skip ...string) []string {
+	var names []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		skipped := false
+		for _, prefix := range skip {
+			if strings.HasPrefix(line, prefix) {
+				skipped = true
+				break
+			}
+		}
+		if skipped {
+			continue
+		}
+		names = append(names, strings.Fields(line)[0])
+	}
+	return names
+}
+
+// packageManagers holds the supported package managers by name
+var packageManagers = map[string]PackageManager{
+	"apt": &cliManager{
+		name:       "apt",
+		binary:     "apt-get",
+		privileged: true,
+		env: []string{
+			// Avoid debconf prompts for tzdata and friends
+			"DEBIAN_FRONTEND=noninteractive",
+			"DEBCONF_NONINTERACTIVE_SEEN=true",
+			"DEBCONF_NOWARNINGS=yes",
+		},
+		install:    []string{"apt-get", "install", "-y"},
+		remove:     []string{"apt-get", "remove", "-y"},
+		upgrade:    []string{"apt-get", "install", "--only-upgrade", "-y"},
+		upgradeAll: []string{"apt-get", "upgrade", "-y"},
+		query:      []string{"dpkg-query", "-W", "-f=${db:Status-Status} ${Version}\n"},
+		search:     []string{"apt-cache", "search", "--names-only"},
+		parseVersion: func(_ string, out string) string {
+			// Removed packages keep a dpkg entry with config-files status
+			status, version, _ := strings.Cut(strings.TrimSpace(out), " ")
+			if status != "installed" {
+				return ""
+			}
+			return version
+		},
+		parseSearch: func(out string) []string {
+			return firstFields(out)
+		},
+		fetch: func(dir string) []string {
+			return []string{"apt-get", "install", "--download-only", "-y", "-o", "Dir::Cache::archives=" + dir}
+		},
+		installLocal: []string{"apt-get", "install", "-y", "--no-download"},
+		packageExts:  []string{".deb"},
+	},
+	"dnf": &cliManager{
+		name:         "dnf",
+		binary:       "dnf",
+		privileged:   true,
+		install:      []string{"dnf", "install", "-y"},
+		remove:       []string{"dnf", "remove", "-y"},
+		upgrade:      []string{"dnf", "upgrade", "-y"},
+		upgradeAll:   []string{"dnf", "upgrade", "-y"},
+		query:        []string{"rpm", "-q", "--qf", "%{VERSION}-%{RELEASE}\n"},
+		search:       []string{"dnf", "search", "-q"},
+		parseVersion: lastField,
+		parseSearch:  parseRPMSearch,
+		fetch: func(dir string) []string {
+			return []string{"dnf", "download", "--resolve", "--alldeps", "--destdir", dir}
+		},
+		installLocal: []string{"dnf", "install", "-y", "--disablerepo=*"},
+		packageExts:  []string{".rpm"},
+	},
+	"yum": &cliManager{
+		name:         "yum",
+		binary:       "yum",
+		privileged:   true,
+		install:      []string{"yum", "install", "-y"},
+		remove:       []string{"yum", "remove", "-y"},
+		upgrade:      []string{"yum", "update", "-y"},
+		upgradeAll:   []string{"yum", "update", "-y"},
+		query:        []string{"rpm", "-q", "--qf", "%{VERSION}-%{RELEASE}\n"},
+		search:       []string{"yum", "search", "-q"},
+		parseVersion: lastField,
+		parseSearch:  parseRPMSearch,
+		fetch: func(dir string) []string {
+			return []string{"yumdownloader", "--resolve", "--destdir", dir}
+		},
+		installLocal: []string{"yum", "localinstall", "-y", "--disablerepo=*"},
+		packageExts:  []string{".rpm"},
+	},
+	"pacman": &cliManager{
+		name:         "pacman",
+		binary:       "pacman",
+		privileged:   true,
+		install:      []string{"pacman", "-S", "--noconfirm"},
+		remove:       []string{"pacman", "-R", "--noconfirm"},
+		upgrade:      []string{"pacman", "-S", "--noconfirm"},
+		upgradeAll:   []string{"pacman", "-Syu", "--noconfirm"},
+		query:        []string{"pacman", "-Q"},
+		search:       []string{"pacman", "-Ssq"},
+		parseVersion: lastField,
+		parseSearch: func(out string) []string {
+			return firstFields(out)
+		},
+		fetch: func(dir string) []string {
+			return []string{"pacman", "-Sw", "--noconfirm", "--cachedir", dir}
+		},
+		installLocal: []string{"pacman", "-U", "--needed", "--noconfirm"},
+		packageExts:  []string{".pkg.tar.zst", ".pkg.tar.xz"},
+	},
+	"brew": &cliManager{
+		name:   "brew",
+		binary: "brew",
+		// Homebrew refuses to run as root and doesn't prompt by default
+		install:      []string{"brew", "install"},
+		remove:       []string{"brew", "uninstall"},
+		upgrade:      []string{"brew", "upgrade"},
+		upgradeAll:   []string{"brew", "upgrade"},
+		query:        []string{"brew", "list", "--versions"},
+		search:       []string{"brew", "search"},
+		parseVersion: lastField,
+		parseSearch: func(out string) []string {
+			return firstFields(out, "==>")
+		},
+	},
+	"cask": &cliManager{
+		name:         "cask",
+		binary:       "brew",
+		install:      []string{"brew", "install", "--cask"},
+		remove:       []string{"brew", "uninstall", "--cask"},
+		upgrade:      []string{"brew", "upgrade", "--cask"},
+		upgradeAll:   []string{"brew", "upgrade", "--cask"},
+		query:        []string{"brew", "list", "--cask", "--versions"},
+		search:       []string{"brew", "search", "--cask"},
+		parseVersion: lastField,
+		parseSearch: func(out string) []string {
+			return firstFields(out, "==>")
+		},
+	},
+	"zypper": &cliManager{
+		name:         "zypper",
+		binary:       "zypper",
+		privileged:   true,
+		install:      []string{"zypper", "--non-interactive", "install"},
+		remove:       []string{"zypper", "--non-interactive", "remove"},
+		upgrade:      []string{"zypper", "--non-interactive", "update"},
+		upgradeAll:   []string{"zypper", "--non-interactive", "update"},
+		query:        []string{"rpm", "-q", "--qf", "%{VERSION}-%{RELEASE}\n"},
+		search:       []string{"zypper", "--non-interactive", "--quiet", "search"},
+		parseVersion: lastField,
+		parseSearch:  parseZypperSearch,
+	},
+	"apk": &cliManager{
+		name:       "apk",
+		binary:     "apk",
+		privileged: true,
+		// apk never prompts
+		install:    []string{"apk", "add"},
+		remove:     []string{"apk", "del"},
+		upgrade:    []string{"apk", "upgrade"},
+		upgradeAll: []string{"apk", "upgrade", "--update-cache"},
+		query:      []string{"apk", "list", "--installed"},
+		search:     []string{"apk", "search"},
+		parseVersion: func(pkg, out string) string {
+			// ripgrep-14.1.0-r0 x86_64 {ripgrep} (MIT) [installed]
+			return trimName(pkg, firstField(out))
+		},
+		parseSearch: func(out string) []string {
+			return stripVersions(firstFields(out), apkVersion)
+		},
+	},
+	"xbps": &cliManager{
+		name:       "xbps",
+		binary:     "xbps-install",
+		privileged: true,
+		install:    []string{"xbps-install", "--yes"},
+		remove:     []string{"xbps-remove", "--yes"},
+		upgrade:    []string{"xbps-install", "--yes", "--update"},
+		upgradeAll: []string{"xbps-install", "--yes", "--sync", "--update"},
+		query:      []string{"xbps-query", "--property", "pkgver"},
+		search:     []string{"xbps-query", "--repository", "--search"},
+		parseVersion: func(pkg, out string) string {
+			// ripgrep-14.1.0_1
+			return trimName(pkg, firstField(out))
+		},
+		parseSearch: func(out string) []string {
+			// [-] ripgrep-14.1.0_1    Fast line-oriented regex search tool
+			var names []string
+			for _, line := range strings.Split(out, "\n") {
+				fields := strings.Fields(line)
+				if len(fields) < 2 {
+					continue
+				}
+				names = append(names, fields[1])
+			}
+			return stripVersions(names, xbpsVersion)
+		},
+	},
+	"emerge": &cliManager{
+		name:       "emerge",
+		binary:     "emerge",
+		privileged: true,
+		install:    []string{"emerge", "--ask=n", "--noreplace"},
+		remove:     []string{"emerge", "--ask=n", "--depclean"},
+		upgrade:    []string{"emerge", "--ask=n", "--update"},
+		upgradeAll: []string{"emerge", "--ask=n", "--update", "--deep", "--newuse", "@world"},
+		query:      []string{"portageq", "best_version", "/"},
+		search:     []string{"emerge", "--search"},
+		parseVersion: func(_ string, out string) string {
+			// sys-apps/ripgrep-14.1.0
+			atom := firstField(out)
+			if loc := portageVersion.FindStringIndex(atom); loc != nil {
+				return atom[loc[0]+1:]
+			}
+			return ""
+		},
+		parseSearch: func(out string) []string {
+			// *  sys-apps/ripgrep
+			var names []string
+			for _, line := range strings.Split(out, "\n") {
+				if atom, ok := strings.CutPrefix(line, "*  "); ok {
+					names = append(names, strings.TrimSpace(atom))
+				}
+			}
+			return names
+		},
+	},
+	"nix": &cliManager{
+		name:   "nix",
+		binary: "nix-env",
+		// Per-user profile, no root needed
+		attrPrefix: "nixpkgs.",
+		install:    []string{"nix-env", "--install", "--attr"},
+		remove:     []string{"nix-env", "--uninstall"},
+		upgrade:    []string{"nix-env", "--upgrade"},
+		upgradeAll: []string{"nix-env", "--upgrade"},
+		query:      []string{"nix-env", "--query"},
+		search:     []string{"nix-env", "--query", "--available"},
+		parseVersion: func(pkg, out string) string {
+			// ripgrep-14.1.0
+			return trimName(pkg, firstField(out))
+		},
+		parseSearch: func(out string) []string {
+			return stripVersions(firstFields(out), nixVersion)
+		},
+	},
+	"flatpak": &cliManager{
+		name:   "flatpak",
+		binary: "flatpak",
+		// flatpak asks polkit for system installs itself
+		install:    []string{"flatpak", "install", "--noninteractive", "--assumeyes"},
+		remove:     []string{"flatpak", "uninstall", "--noninteractive", "--assumeyes"},
+		upgrade:    []string{"flatpak", "update", "--noninteractive", "--assumeyes"},
+		upgradeAll: []string{"flatpak", "update", "--noninteractive", "--assumeyes"},
+		query:      []string{"flatpak", "info"},
+		search:     []string{"flatpak", "search", "--columns=application"},
+		parseVersion: func(_ string, out string) string {
+			for _, line := range strings.Split(out, "\n") {
+				if version, ok := strings.CutPrefix(strings.TrimSpace(line), "Version:"); ok {
+					return strings.TrimSpace(version)
+				}
+			}
+			// Installed, but the app doesn't declare a version
+			return "unknown"
+		},
+		parseSearch: func(out string) []string {
+			return firstFields(out, "No matches")
+		},
+	},
+	"snap": &cliManager{
+		name:       "snap",
+		binary:     "snap",
+		privileged: true,
+		// snap never prompts
+		install:    []string{"snap", "install"},
+		remove:     []string{"snap", "remove"},
+		upgrade:    []string{"snap", "refresh"},
+		upgradeAll: []string{"snap", "refresh"},
+		query:      []string{"snap", "list"},
+		search:     []string{"snap", "find"},
+		parseVersion: func(_ string, out string) string {
+			// Name     Version  Rev   Tracking  Publisher  Notes
+			// firefox  120.0    3358  stable    mozilla    -
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) < 2 {
+				return ""
+			}
+			fields := strings.Fields(lines[1])
+			if len(fields) < 2 {
+				return ""
+			}
+			return fields[1]
+		},
+		parseSearch: func(out string) []string {
+			return firstFields(out, "Name ")
+		},
+	},
+
+	// Language package managers, only used when named explicitly
+	"cargo": &cliManager{
+		name:    "cargo",
+		binary:  "cargo",
+		install: []string{"cargo", "install", "--locked"},
+		remove:  []string{"cargo", "uninstall"},
+		// cargo install replaces a crate only if a newer version exists
+		upgrade:   []string{"cargo", "install", "--locked"},
+		query:     []string{"cargo", "install", "--list"},
+		listQuery: true,
+		search:    []string{"cargo", "search", "--limit", "20"},
+		parseVersion: func(pkg, out string) string {
+			// ripgrep v14.1.0:
+			//     rg
+			for _, line := range strings.Split(out, "\n") {
+				if version, ok := strings.CutPrefix(line, pkg+" v"); ok {
+					version, _, _ = strings.Cut(version, ":")
+					version, _, _ = strings.Cut(version, " ")
+					return version
+				}
+			}
+			return ""
+		},
+		parseSearch: func(out string) []string {
+			// ripgrep = "14.1.0"    # description
+			return firstFields(out, "...", "note:")
+		},
+	},
+	"pipx": &cliManager{
+		name:       "pipx",
+		binary:     "pipx",
+		install:    []string{"pipx", "install"},
+		remove:     []string{"pipx", "uninstall"},
+		upgrade:    []string{"pipx", "upgrade"},
+		upgradeAll: []string{"pipx", "upgrade-all"},
+		query:      []string{"pipx", "list", "--short"},
+		listQuery:  true,
+		parseVersion: func(pkg, out string) string {
+			// black 24.1.0
+			for _, line := range strings.Split(out, "\n") {
+				fields := strings.Fields(line)
+				if len(fields) >= 2 && strings.EqualFold(fields[0], pkg) {
+					return fields[1]
+				}
+			}
+			return ""
+		},
+	},
+	"go": &goManager{cliManager{
+		name:    "go",
+		binary:  "go",
+		install: []string{"go", "install"},
+	}},
+	"npm": &cliManager{
+		name:       "npm",
+		binary:     "npm",
+		install:    []string{"npm", "install", "--global"},
+		remove:     []string{"npm", "uninstall", "--global"},
+		upgrade:    []string{"npm", "update", "--global"},
+		upgradeAll: []string{"npm", "update", "--global"},
+		query:      []string{"npm", "ls", "--global", "--depth=0"},
+		listQuery:  true,
+		search:     []string{"npm", "search", "--parseable"},
+		parseVersion: func(pkg, out string) string {
+			// /usr/lib
+			// └── typescript@5.4.5
+			for _, line := range strings.Split(out, "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 0 {
+					continue
+				}
+				if version, ok := strings.CutPrefix(fields[len(fields)-1], pkg+"@"); ok {
+					return version
+				}
+			}
+			return ""
+		},
+		parseSearch: func(out string) []string {
+			return firstFields(out)
+		},
+	},
+}
+
+// detectOrder lists the system package managers to probe on each OS, in
+// order of preference. flatpak and snap are only used when a dependency
+// names them explicitly.
+var detectOrder = map[string][]string{
+	"darwin": {"brew", "nix"},
+	"linux":  {"pacman", "apt", "dnf", "yum", "zypper", "apk", "xbps", "emerge", "nix"},
+}
+
+var (
+	apkVersion     = regexp.MustCompile(`-[0-9][^-]*-r[0-9]+$`)
+	xbpsVersion    = regexp.MustCompile(`-[^-]+_[0-9]+$`)
+	nixVersion     = regexp.MustCompile(`-[0-9][^-]*$`)
+	portageVersion = regexp.MustCompile(`-[0-9][^/]*$`)
+)
+
+// firstField returns the first whitespace separated field of out
+func firstField(out string) string {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// trimName returns the version part of a "name-version" string for pkg
+func trimName(pkg, nameVersion string) string {
+	version, ok := strings.CutPrefix(nameVersion, pkg+"-")
+	if !ok {
+		return ""
+	}
+	return version
+}
+
+// stripVersions removes the version suffix matched by re from each name
+func stripVersions(names []string, re *regexp.Regexp) []string {
+	for i, name := range names {
+		names[i] = re.ReplaceAllString(name, "")
+	}
+	return names
+}
+
+// parseZypperSearch extracts package names from zypper's search table
+//
+//	S | Name    | Summary                  | Type
+//	--+---------+--------------------------+--------
+//	  | ripgrep | A search tool            | package
+func parseZypperSearch(out string) []string {
+	var names []string
+	for _, line := range strings.Split(out, "\n") {
+		cols := strings.Split(line, "|")
+		if len(cols) < 3 || strings.HasPrefix(line, "--") {
+			continue
+		}
+		name := strings.TrimSpace(cols[1])
+		if name == "" || name == "Name" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// parseRPMSearch extracts package names from dnf/yum search output such as
+// "ripgrep.x86_64 : Line-oriented search tool"
+func parseRPMSearch(out string) []string {
+	var names []string
+	for _, name := range firstFields(out, "=", "Last metadata") {
+		name, _, _ = strings.Cut(name, ":")
+		if i := strings.LastIndex(name, "."); i > 0 {
+			name = name[:i]
+		}
+		names = append(names, name)
+	}
+	return names
+}
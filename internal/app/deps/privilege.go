@@ -0,0 +1,16 @@
+package deps
+
+import "github.com/BuddhiLW/arara/internal/pkg/privilege"
+
+// privilegeSession returns the run's privilege escalation session, so
+// credentials are validated once for all packages; replaced in tests
+var privilegeSession = privilege.Shared
+
+// escalate returns args prefixed with the configured escalation tool
+func escalate(args []string) ([]string, error) {
+	s, err := privilegeSession()
+	if err != nil {
+		return nil, err
+	}
+	return s.Command(args...), nil
+}
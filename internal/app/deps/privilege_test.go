@@ -0,0 +1,45 @@
+package deps
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/privilege"
+)
+
+func TestEscalate(t *testing.T) {
+	var got []string
+	origRun, origSession := runCmd, privilegeSession
+	runCmd = func(env []string, args ...string) error {
+		got = append(got, strings.Join(args, " "))
+		return nil
+	}
+	defer func() { runCmd, privilegeSession = origRun, origSession }()
+
+	testCases := []struct {
+		tool string
+		want []string
+	}{
+		{"doas", []string{"doas apt-get install -y git", "cargo install --locked bat"}},
+		{privilege.None, []string{"apt-get install -y git", "cargo install --locked bat"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.tool, func(t *testing.T) {
+			got = nil
+			privilegeSession = func() (*privilege.Session, error) {
+				return &privilege.Session{Tool: tc.tool}, nil
+			}
+			if err := packageManagers["apt"].Install("git"); err != nil {
+				t.Fatal(err)
+			}
+			// Language managers never escalate
+			if err := packageManagers["cargo"].Install("bat"); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("ran %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
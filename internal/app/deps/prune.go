@@ -0,0 +1,142 @@
+package deps
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/BuddhiLW/arara/internal/pkg/state"
+)
+
+// recordInstalled remembers the packages installDependencies installed
+// for namespace ns, so 'deps prune' can remove them once undeclared
+func recordInstalled(ns string, statuses []depStatus) error {
+	if ns == "" {
+		return nil
+	}
+	var installed []depStatus
+	for _, s := range statuses {
+		if s.Installed {
+			installed = append(installed, s)
+		}
+	}
+	if len(installed) == 0 {
+		return nil
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	for _, s := range installed {
+		st.MarkPackageInstalled(ns, state.PackageRecord{
+			Name:    s.Name,
+			Manager: s.Manager.Name(),
+			Package: s.Package,
+		})
+	}
+	return st.Save()
+}
+
+// pruneDependencies uninstalls the packages arara installed for ns whose
+// dependency is no longer declared, after asking for confirmation unless
+// yes is set. Packages another namespace also installed are only
+// forgotten for ns. Packages arara didn't install are never touched.
+func pruneDependencies(st *state.State, ns string, declared []string, dryRun, yes bool) error {
+	isDeclared := make(map[string]bool)
+	for _, name := range declared {
+		isDeclared[name] = true
+	}
+
+	var prune []state.PackageRecord
+	for _, rec := range st.InstalledPackages(ns) {
+		if isDeclared[rec.Name] {
+			continue
+		}
+		if others := otherNamespaces(st.PackageNamespaces(rec.Key()), ns); len(others) > 0 {
+			fmt.Fprintf(Stdout, "Keeping %s: still installed for %s\n", rec.Name, strings.Join(others, ", "))
+			if !dryRun {
+				st.MarkPackageRemoved(ns, rec.Key())
+			}
+			continue
+		}
+		prune = append(prune, rec)
+	}
+
+	if len(prune) == 0 {
+		fmt.Fprintln(Stdout, "Nothing to prune")
+		if dryRun {
+			return nil
+		}
+		return st.Save()
+	}
+
+	fmt.Fprintln(Stdout, "Packages installed by arara that are no longer declared:")
+	for _, rec := range prune {
+		fmt.Fprintf(Stdout, "  %s (%s %s)\n", rec.Name, rec.Manager, rec.Package)
+	}
+	if dryRun {
+		return nil
+	}
+	if !yes {
+		fmt.Fprintf(Stdout, "Remove %d packages? [y/N] ", len(prune))
+		scanner := bufio.NewScanner(Stdin)
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		if answer := strings.ToLower(strings.TrimSpace(scanner.Text())); answer != "y" && answer != "yes" {
+			fmt.Fprintln(Stdout, "Cancelled")
+			return nil
+		}
+	}
+
+	// Remove in batches per package manager, in the order they appear
+	batches := make(map[string][]state.PackageRecord)
+	var order []string
+	for _, rec := range prune {
+		if batches[rec.Manager] == nil {
+			order = append(order, rec.Manager)
+		}
+		batches[rec.Manager] = append(batches[rec.Manager], rec)
+	}
+
+	var errs []string
+	for _, name := range order {
+		recs := batches[name]
+		pm, err := lookupManager(name)
+		if err == nil {
+			pkgs := make([]string, len(recs))
+			for i, rec := range recs {
+				pkgs[i] = rec.Package
+			}
+			fmt.Fprintf(Stdout, "Removing %d dependencies using %s...\n", len(pkgs), name)
+			err = pm.Remove(pkgs...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		for _, rec := range recs {
+			st.MarkPackageRemoved(ns, rec.Key())
+		}
+	}
+
+	if err := st.Save(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to remove packages: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// otherNamespaces returns namespaces without ns
+func otherNamespaces(namespaces []string, ns string) []string {
+	var others []string
+	for _, n := range namespaces {
+		if n != ns {
+			others = append(others, n)
+		}
+	}
+	return others
+}
@@ -0,0 +1,142 @@
+package deps
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/state"
+)
+
+func TestInstallRecordsPackages(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	installed := make(map[string]bool)
+	origRun, origLook, origOutput := runCmd, lookPath, outputCmd
+	runCmd = func(env []string, args ...string) error {
+		installed[args[len(args)-1]] = true
+		return nil
+	}
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	outputCmd = func(args ...string) (string, error) {
+		// git was installed before arara ran
+		pkg := args[len(args)-1]
+		if pkg == "git" || installed[pkg] {
+			return pkg + " 1.0-1\n", nil
+		}
+		return "", exec.Command("false").Run()
+	}
+	defer func() { runCmd, lookPath, outputCmd = origRun, origLook, origOutput }()
+
+	statuses, err := installDependencies(plain("pacman:git", "pacman:bat"), "")
+	if err != nil {
+		t.Fatalf("installDependencies() error = %v", err)
+	}
+	if err := recordInstalled("work", statuses); err != nil {
+		t.Fatalf("recordInstalled() error = %v", err)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := st.InstalledPackages("work")
+	if len(recs) != 1 || recs[0].Name != "pacman:bat" || recs[0].Key() != "pacman:bat" {
+		t.Errorf("recorded %+v, want only bat", recs)
+	}
+}
+
+func TestPruneDependencies(t *testing.T) {
+	var got []string
+	origRun, origLook, origStdin, origStdout := runCmd, lookPath, Stdin, Stdout
+	runCmd = func(env []string, args ...string) error {
+		got = append(got, strings.Join(args, " "))
+		return nil
+	}
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { runCmd, lookPath, Stdin, Stdout = origRun, origLook, origStdin, origStdout }()
+
+	newState := func(t *testing.T) *state.State {
+		t.Setenv("XDG_STATE_HOME", t.TempDir())
+		st, err := state.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, rec := range []state.PackageRecord{
+			{Name: "fd", Manager: "apt", Package: "fd-find"},
+			{Name: "tmux", Manager: "apt", Package: "tmux"},
+			{Name: "ripgrep", Manager: "cargo", Package: "ripgrep"},
+			{Name: "neovim", Manager: "apt", Package: "neovim"},
+		} {
+			st.MarkPackageInstalled("work", rec)
+		}
+		st.MarkPackageInstalled("home", state.PackageRecord{Name: "neovim", Manager: "apt", Package: "neovim"})
+		return st
+	}
+	declared := []string{"tmux", "git"}
+
+	testCases := []struct {
+		name     string
+		input    string
+		dryRun   bool
+		yes      bool
+		wantRun  []string
+		wantLeft []string // packages still recorded for work
+	}{
+		{
+			name:     "confirmed",
+			input:    "y\n",
+			wantRun:  []string{"sudo apt-get remove -y fd-find", "cargo uninstall ripgrep"},
+			wantLeft: []string{"tmux"},
+		},
+		{
+			name:     "yes",
+			yes:      true,
+			wantRun:  []string{"sudo apt-get remove -y fd-find", "cargo uninstall ripgrep"},
+			wantLeft: []string{"tmux"},
+		},
+		{
+			name:     "cancelled",
+			input:    "n\n",
+			wantLeft: []string{"fd", "ripgrep", "tmux"},
+		},
+		{
+			name:     "dry run",
+			dryRun:   true,
+			wantLeft: []string{"fd", "neovim", "ripgrep", "tmux"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got = nil
+			Stdin = strings.NewReader(tc.input)
+			var out bytes.Buffer
+			Stdout = &out
+
+			st := newState(t)
+			if err := pruneDependencies(st, "work", declared, tc.dryRun, tc.yes); err != nil {
+				t.Fatalf("pruneDependencies() error = %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.wantRun, "\n") {
+				t.Errorf("ran:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tc.wantRun, "\n"))
+			}
+
+			var left []string
+			for _, rec := range st.InstalledPackages("work") {
+				left = append(left, rec.Name)
+			}
+			if strings.Join(left, " ") != strings.Join(tc.wantLeft, " ") {
+				t.Errorf("still recorded %v, want %v", left, tc.wantLeft)
+			}
+			// neovim is still needed by home
+			if !strings.Contains(out.String(), "Keeping neovim: still installed for home") {
+				t.Errorf("output doesn't explain keeping neovim:\n%s", out.String())
+			}
+			if strings.Contains(strings.Join(got, " "), "neovim") {
+				t.Error("neovim must not be removed")
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package deps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// satisfies reports whether the installed version meets constraint, a
+// comma separated list of comparisons such as ">=0.9" or ">=0.9,<0.11".
+// Supported operators are >=, <=, >, <, == (or =) and !=.
+func satisfies(version, constraint string) (bool, error) {
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		op := strings.TrimRightFunc(part, func(r rune) bool {
+			return !strings.ContainsRune("<>=!", r)
+		})
+		want := strings.TrimSpace(part[len(op):])
+		if want == "" {
+			return false, fmt.Errorf("invalid version constraint: %s", part)
+		}
+
+		cmp := compareVersions(version, want)
+		var ok bool
+		switch op {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		case "==", "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		default:
+			return false, fmt.Errorf("invalid version constraint: %s", part)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// compareVersions compares the upstream parts of two versions and returns
+// -1, 0 or 1. The epoch ("1:"), a leading "v" and the distro release
+// suffix ("-1", "-r0", "_1", "+deb12u1", "~rc1") are ignored, so
+// "1:0.9.5-1.fc40" compares equal to "0.9.5". Missing components count as
+// zero; numeric components compare numerically, others lexically.
+func compareVersions(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		x, y := "0", "0"
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if c := comparePart(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// versionParts splits the upstream version of v into its components
+func versionParts(v string) []string {
+	if _, rest, ok := strings.Cut(v, ":"); ok {
+		v = rest
+	}
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-_+~"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.FieldsFunc(v, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// comparePart compares version components by their leading number, then
+// by the remainder ("5" < "10", "1a" < "1b")
+func comparePart(x, y string) int {
+	xn, xr := leadingNumber(x)
+	yn, yr := leadingNumber(y)
+	switch {
+	case xn < yn:
+		return -1
+	case xn > yn:
+		return 1
+	}
+	return strings.Compare(xr, yr)
+}
+
+// leadingNumber splits s into its leading digits as a number and the rest
+func leadingNumber(s string) (int, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	n, _ := strconv.Atoi(s[:i])
+	return n, s[i:]
+}
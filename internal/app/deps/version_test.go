@@ -0,0 +1,57 @@
+package deps
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{"0.9.5", "0.9", 1},
+		{"0.9", "0.9.0", 0},
+		{"0.10.1", "0.9.5", 1},
+		{"1:2.39.2-1", "2.39.2", 0}, // epoch and debian revision
+		{"0.9.5-1.fc40", "0.9.5", 0},
+		{"v0.16.1", "0.16.1", 0},
+		{"14.1.0-r0", "14.1.0", 0},
+		{"14.1.0_1", "14.1", 0},
+		{"2.0rc1", "2.0rc2", -1},
+		{"1.2", "1.10", -1},
+	}
+	for _, tc := range testCases {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	testCases := []struct {
+		version    string
+		constraint string
+		want       bool
+		wantErr    bool
+	}{
+		{"0.9.5-1", ">=0.9", true, false},
+		{"0.8.3", ">=0.9", false, false},
+		{"0.10.1", ">=0.9,<0.10", false, false},
+		{"0.9.5", ">=0.9, <0.10", true, false},
+		{"0.9.5", "==0.9.5", true, false},
+		{"0.9.5", "=0.9.5", true, false},
+		{"0.9.5", "!=0.9.5", false, false},
+		{"1.0", ">1.0", false, false},
+		{"1.0", "<=1.0", true, false},
+		{"1.0", "~>1.0", false, true},
+		{"1.0", ">=", false, true},
+	}
+	for _, tc := range testCases {
+		got, err := satisfies(tc.version, tc.constraint)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("satisfies(%q, %q) error = %v, wantErr %v", tc.version, tc.constraint, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("satisfies(%q, %q) = %v, want %v", tc.version, tc.constraint, got, tc.want)
+		}
+	}
+}
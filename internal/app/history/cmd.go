@@ -0,0 +1,72 @@
+package history
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rwxrob/bonzai"
+	"github.com/rwxrob/bonzai/cmds/help"
+
+	"github.com/BuddhiLW/arara/internal/pkg/runlog"
+)
+
+var Cmd = &bonzai.Cmd{
+	Name:    "history",
+	Alias:   "hist",
+	Short:   "list recorded install and build runs",
+	Usage:   "history [script-name]",
+	MaxArgs: 1,
+	Long: `
+List every recorded 'arara install' and 'arara build install' run, newest
+first. Each run shows its ID, start time, kind, script, exit code and
+duration. Pass a script name to only show its runs.
+
+Runs are stored under $XDG_STATE_HOME/arara/runs (defaults to
+~/.local/state/arara/runs). Use 'arara logs <run-id>' to see the output
+captured for a run.
+
+Examples:
+  arara history
+  arara history docker
+`,
+	Cmds: []*bonzai.Cmd{help.Cmd},
+	Do: func(caller *bonzai.Cmd, args ...string) error {
+		runs, err := runlog.List()
+		if err != nil {
+			return err
+		}
+
+		var filter string
+		if len(args) > 0 {
+			filter = args[0]
+		}
+
+		shown := 0
+		for _, run := range runs {
+			if filter != "" && run.Script != filter {
+				continue
+			}
+			if shown == 0 {
+				fmt.Println("Recorded runs:")
+			}
+			status := "ok"
+			if run.ExitCode != 0 {
+				status = fmt.Sprintf("exit %d", run.ExitCode)
+			}
+			fmt.Printf("  %s  %s  %-7s %-20s %-8s %s\n",
+				run.ID,
+				run.Start.Format(time.DateTime),
+				run.Kind,
+				run.Script,
+				status,
+				run.Duration().Round(time.Millisecond),
+			)
+			shown++
+		}
+
+		if shown == 0 {
+			fmt.Println("No runs recorded")
+		}
+		return nil
+	},
+}
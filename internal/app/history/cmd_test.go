@@ -0,0 +1,31 @@
+package history_test
+
+import (
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/app/history"
+	"github.com/BuddhiLW/arara/internal/pkg/runlog"
+)
+
+func TestHistoryCmd(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	// Empty history
+	if err := history.Cmd.Do(history.Cmd); err != nil {
+		t.Fatalf("history.Cmd.Do() error = %v", err)
+	}
+
+	run, err := runlog.Begin("install", "docker", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run.Finish(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{}, {"docker"}, {"other"}} {
+		if err := history.Cmd.Do(history.Cmd, args...); err != nil {
+			t.Errorf("history.Cmd.Do(%v) error = %v", args, err)
+		}
+	}
+}
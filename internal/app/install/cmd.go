@@ -2,12 +2,21 @@ package install
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 
+	"github.com/BuddhiLW/arara/internal/app/compat"
+	"github.com/BuddhiLW/arara/internal/app/deps"
 	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/privilege"
+	"github.com/BuddhiLW/arara/internal/pkg/runlog"
+	"github.com/BuddhiLW/arara/internal/pkg/state"
+	"github.com/BuddhiLW/arara/internal/pkg/vars"
 	"github.com/rwxrob/bonzai"
 	"github.com/rwxrob/bonzai/cmds/help"
 	bonzaiVars "github.com/rwxrob/bonzai/vars"
@@ -17,9 +26,87 @@ var Cmd = &bonzai.Cmd{
 	Name:  "install",
 	Alias: "i",
 	Short: "install additional tools",
+	Usage: "install [-f] [--pick | --upgrade-all | [--uninstall] <name> [-- args]]",
 	Long: `
 	Install additional tools and configurations from the scripts directory.
 	Scripts are defined in arara.yaml and executed with proper environment setup.
+
+	Before running, the script's compat requirements are checked against the
+	current system. Incompatible scripts are refused unless --force is given.
+	The listing marks each script as compatible (✓) or not (✗).
+
+	Packages a script lists in dependencies, or in a '# arara: depends='
+	header picked up by 'arara sync', are checked the same way and must be
+	installed first, with 'arara deps install', unless --force is given.
+
+	# Picking scripts
+
+	arara install --pick lists every script of the active namespace with its
+	description and compat status. Toggle scripts by number, confirm the
+	summary, and the selected scripts are installed in arara.yaml order with
+	progress shown for each one.
+
+	# Integrity
+
+	A script with a sha256 field (see 'arara sync --pin') is hashed before
+	it runs and refused if the hash does not match. Its uninstall and
+	upgrade actions are checked against action_sha256 the same way, and
+	an action of a pinned script that was never pinned is refused. --force
+	does not skip these checks.
+
+	# Uninstall and upgrade
+
+	Scripts may declare uninstall and upgrade actions. Without an explicit
+	path, scripts/uninstall/<name> and scripts/upgrade/<name> are used when
+	they exist:
+
+	  arara install --uninstall docker   # run the uninstall action
+	  arara install --upgrade-all        # upgrade every installed script
+
+	Successful installs are recorded in $XDG_STATE_HOME/arara/state.yaml so
+	--upgrade-all only touches scripts installed on this machine. The
+	listing marks them as [installed].
+
+	# Arguments
+
+	Everything after -- is passed to the script. Without --, the script's
+	default args list from arara.yaml is used:
+
+	  scripts:
+	    install:
+	      - name: neovim
+	        path: scripts/install/neovim
+	        uninstall: scripts/uninstall/neovim
+	        args: ["--stable"]
+	        env:
+	          NVIM_PREFIX: $HOME/.local
+
+	# Environment
+
+	Scripts inherit the current environment plus the namespace env map, the
+	script's own env map, and these variables:
+
+	  ARARA_NAMESPACE   active namespace
+	  ARARA_DOTFILES    path to the dotfiles repository
+	  ARARA_SCRIPT      path to the script being executed
+	  ARARA_SCRIPT_DIR  directory containing the script
+	  ARARA_OS          operating system (runtime.GOOS)
+	  ARARA_ARCH        architecture (runtime.GOARCH)
+
+	# Privileged scripts
+
+	Scripts that need root declare privileged: true instead of calling sudo
+	themselves. They run through the escalation tool set by the privilege
+	var (ARARA_PRIVILEGE) or the privilege field of the global config: sudo,
+	doas, run0, pkexec, none, or auto (the default: none as root, else the
+	first of those installed). Credentials are asked for once per run.
+
+	# Inherited scripts
+
+	A namespace whose arara.yaml has extends: <namespace> also offers the
+	scripts of that base namespace, unless it defines a script of the same
+	name. Inherited scripts run from the base repository, and ARARA_DOTFILES
+	points there.
 	`,
 	Cmds: []*bonzai.Cmd{
 		help.Cmd,
@@ -36,92 +123,538 @@ var Cmd = &bonzai.Cmd{
 		}
 
 		// Load config to get environment variables
-		cfg, err := config.LoadConfig(filepath.Join(dotfilesPath, "arara.yaml"))
+		cfg, err := config.LoadMergedConfig(filepath.Join(dotfilesPath, "arara.yaml"))
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		// Set environment variables from config into vars.Data
-		for k, v := range cfg.Env {
-			expanded := os.ExpandEnv(v)
-			bonzaiVars.Data.Set(k, expanded)
+		// Separate flags from positional args; everything after -- belongs
+		// to the script
+		var force, uninstall, upgradeAll, pick bool
+		var rest, scriptArgs []string
+		passArgs := false
+		for i, arg := range args {
+			if arg == "--" {
+				scriptArgs = append([]string{}, args[i+1:]...)
+				passArgs = true
+				break
+			}
+			switch arg {
+			case "--force", "-f":
+				force = true
+			case "--uninstall":
+				uninstall = true
+			case "--upgrade-all":
+				upgradeAll = true
+			case "--pick", "-p":
+				pick = true
+			default:
+				rest = append(rest, arg)
+			}
+		}
+
+		ns := bonzaiVars.Fetch(vars.ActiveNamespaceEnv, vars.ActiveNamespaceVar, "")
+		st, err := state.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load state: %w", err)
+		}
+
+		if upgradeAll {
+			return upgradeInstalled(cfg, st, ns, dotfilesPath, force)
+		}
+
+		if pick {
+			return pickScripts(cfg, st, ns, dotfilesPath, force)
 		}
 
 		// If no args, list available scripts
-		if len(args) == 0 {
+		if len(rest) == 0 {
 			fmt.Println("Available installation scripts:")
 			for _, script := range cfg.Scripts.Install {
-				fmt.Printf("  %s - %s\n", script.Name, script.Description)
+				installed := ""
+				if st.IsInstalled(ns, script.Name) {
+					installed = " [installed]"
+				}
+				failures := compat.Failures(compat.FromConfig(script.Compat))
+				if len(failures) == 0 {
+					fmt.Printf("  ✓ %s - %s%s\n", script.Name, script.Description, installed)
+				} else {
+					fmt.Printf("  ✗ %s - %s%s (%s)\n", script.Name, script.Description, installed, failures[0])
+				}
 			}
 			return nil
 		}
 
 		// Find script and execute
-		scriptName := args[0]
-		for _, script := range cfg.Scripts.Install {
-			if script.Name == scriptName {
-				scriptPath := filepath.Join(dotfilesPath, script.Path)
-				return executeCmd.Do(executeCmd, scriptPath)
+		script, ok := findScript(cfg, rest[0])
+		if !ok {
+			return fmt.Errorf("script not found: %s", rest[0])
+		}
+		if err := checkCompat(script, force); err != nil {
+			return err
+		}
+
+		if uninstall {
+			path, err := actionPath(dotfilesPath, script, "uninstall")
+			if err != nil {
+				return err
+			}
+			if err := verifyAction(script, "uninstall", path); err != nil {
+				return err
+			}
+			if err := verifyPinnedPath(cfg, dotfilesPath, path); err != nil {
+				return err
 			}
+			env := scriptEnv(cfg, script, scriptDir(dotfilesPath, script), path)
+			if err := runScript("uninstall", script.Name, path, scriptArgs, env, script.Privileged); err != nil {
+				return err
+			}
+			st.MarkUninstalled(ns, script.Name)
+			return st.Save()
 		}
 
-		return fmt.Errorf("script not found: %s", scriptName)
+		if !passArgs {
+			scriptArgs = nil
+		}
+		if err := checkDependencies(script, force); err != nil {
+			return err
+		}
+		return installScript(cfg, st, ns, dotfilesPath, script, scriptArgs)
 	},
 }
 
+// installScript runs the install action of script and records it as
+// installed. A nil args uses the script's default args.
+func installScript(cfg *config.DotfilesConfig, st *state.State, ns, dotfilesPath string, script config.Script, args []string) error {
+	dotfilesPath = scriptDir(dotfilesPath, script)
+	scriptPath := filepath.Join(dotfilesPath, script.Path)
+	if err := verifyPin(script, scriptPath); err != nil {
+		return err
+	}
+
+	env := scriptEnv(cfg, script, dotfilesPath, scriptPath)
+	if args == nil {
+		args = expandArgs(script.Args, env)
+	}
+	if err := runScript("install", script.Name, scriptPath, args, env, script.Privileged); err != nil {
+		return err
+	}
+
+	hash, _ := runlog.FileHash(scriptPath)
+	st.MarkInstalled(ns, script.Name, hash)
+	return st.Save()
+}
+
+// verifyPin refuses to continue when script pins a sha256 that does not
+// match the file at path
+func verifyPin(script config.Script, path string) error {
+	if script.SHA256 == "" {
+		return nil
+	}
+
+	actual, err := runlog.FileHash(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash script %s: %w", path, err)
+	}
+	if strings.EqualFold(actual, script.SHA256) {
+		return nil
+	}
+
+	fmt.Printf("Integrity check failed for script %s (%s):\n", script.Name, path)
+	fmt.Printf("  expected sha256: %s\n", script.SHA256)
+	fmt.Printf("  actual   sha256: %s\n", actual)
+	fmt.Println("If the change is intended, re-pin it with 'arara sync --pin'")
+	return fmt.Errorf("checksum mismatch for script: %s", script.Name)
+}
+
+// verifyAction checks the file at path implementing action (uninstall or
+// upgrade) against its pin in script. An unpinned action of a pinned
+// script is refused, since it could run as root unchecked.
+func verifyAction(script config.Script, action, path string) error {
+	pin := script.ActionSHA256[action]
+	if pin == "" {
+		if script.SHA256 == "" {
+			return nil
+		}
+		return fmt.Errorf("%s action of pinned script %s is not pinned; pin it with 'arara sync --pin'", action, script.Name)
+	}
+	script.SHA256 = pin
+	return verifyPin(script, path)
+}
+
+// verifyPinnedPath checks the file at path against the pin of every
+// script that runs it, as its install script or one of its actions, so
+// a pinned file stays verified whichever script or command runs it
+func verifyPinnedPath(cfg *config.DotfilesConfig, dotfilesPath, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve script path: %w", err)
+	}
+	matches := func(file string) bool {
+		pinned, err := filepath.Abs(file)
+		return err == nil && pinned == abs
+	}
+	for _, script := range cfg.Scripts.Install {
+		if script.SHA256 == "" && len(script.ActionSHA256) == 0 {
+			continue
+		}
+		if matches(filepath.Join(scriptDir(dotfilesPath, script), script.Path)) {
+			if err := verifyPin(script, path); err != nil {
+				return err
+			}
+		}
+		for _, action := range []string{"uninstall", "upgrade"} {
+			if file, err := actionPath(dotfilesPath, script, action); err == nil && matches(file) {
+				if err := verifyAction(script, action, path); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findScript returns the install script called name
+func findScript(cfg *config.DotfilesConfig, name string) (config.Script, bool) {
+	for _, script := range cfg.Scripts.Install {
+		if script.Name == name {
+			return script, true
+		}
+	}
+	return config.Script{}, false
+}
+
+// scriptDir returns the dotfiles path script paths are relative to: the
+// base namespace's for a script inherited through extends
+func scriptDir(dotfilesPath string, script config.Script) string {
+	if script.Dir != "" {
+		return script.Dir
+	}
+	return dotfilesPath
+}
+
+// actionPath resolves the script implementing action (uninstall or
+// upgrade) for script, falling back to scripts/<action>/<name>
+func actionPath(dotfilesPath string, script config.Script, action string) (string, error) {
+	dotfilesPath = scriptDir(dotfilesPath, script)
+	var path string
+	switch action {
+	case "uninstall":
+		path = script.Uninstall
+	case "upgrade":
+		path = script.Upgrade
+	}
+	if path == "" {
+		path = filepath.Join("scripts", action, script.Name)
+	}
+
+	path = filepath.Join(dotfilesPath, path)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no %s script for %s: %w", action, script.Name, err)
+	}
+	return path, nil
+}
+
+// upgradeInstalled runs the upgrade action of every script recorded as
+// installed in namespace ns. Scripts without an upgrade action are skipped.
+func upgradeInstalled(cfg *config.DotfilesConfig, st *state.State, ns, dotfilesPath string, force bool) error {
+	installed := st.InstalledScripts(ns)
+	if len(installed) == 0 {
+		fmt.Println("No installed scripts to upgrade")
+		return nil
+	}
+
+	var failed []string
+	for _, name := range installed {
+		script, ok := findScript(cfg, name)
+		if !ok {
+			fmt.Printf("Skipping %s: no longer defined in arara.yaml\n", name)
+			continue
+		}
+		path, err := actionPath(dotfilesPath, script, "upgrade")
+		if err != nil {
+			fmt.Printf("Skipping %s: no upgrade script\n", name)
+			continue
+		}
+		if err := checkCompat(script, force); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = append(failed, name)
+			continue
+		}
+
+		if err := verifyAction(script, "upgrade", path); err == nil {
+			err = verifyPinnedPath(cfg, dotfilesPath, path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = append(failed, name)
+			continue
+		}
+
+		fmt.Printf("Upgrading %s...\n", name)
+		env := scriptEnv(cfg, script, scriptDir(dotfilesPath, script), path)
+		if err := runScript("upgrade", name, path, nil, env, script.Privileged); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = append(failed, name)
+			continue
+		}
+
+		hash, _ := runlog.FileHash(filepath.Join(scriptDir(dotfilesPath, script), script.Path))
+		st.MarkUpgraded(ns, name, hash)
+	}
+
+	if err := st.Save(); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to upgrade: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// checkCompat reports unmet compat requirements of script and refuses to
+// continue unless force is set
+func checkCompat(script config.Script, force bool) error {
+	failures := compat.Failures(compat.FromConfig(script.Compat))
+	if len(failures) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Script %s is not compatible with this system:\n", script.Name)
+	for _, f := range failures {
+		fmt.Printf("  ✗ %s\n", f)
+	}
+
+	if !force {
+		return fmt.Errorf("incompatible script: %s (use --force to run anyway)", script.Name)
+	}
+
+	fmt.Println("Running anyway (--force)")
+	return nil
+}
+
+// checkDependencies reports the packages script depends on that are not
+// installed and refuses to continue unless force is set
+func checkDependencies(script config.Script, force bool) error {
+	if len(script.Dependencies) == 0 {
+		return nil
+	}
+
+	missing, err := deps.MissingPackages(script.Dependencies)
+	if err != nil {
+		if !force {
+			return fmt.Errorf("failed to check dependencies of %s: %w (use --force to run anyway)", script.Name, err)
+		}
+		fmt.Printf("Could not check dependencies of %s: %v\n", script.Name, err)
+		fmt.Println("Running anyway (--force)")
+		return nil
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Script %s depends on packages that are not installed:\n", script.Name)
+	for _, pkg := range missing {
+		fmt.Printf("  ✗ %s\n", pkg)
+	}
+
+	if !force {
+		fmt.Printf("Install them with 'arara deps install %s'\n", strings.Join(missing, " "))
+		return fmt.Errorf("missing dependencies: %s (use --force to run anyway)", script.Name)
+	}
+
+	fmt.Println("Running anyway (--force)")
+	return nil
+}
+
+// scriptEnv builds the environment for a script run. Later entries win:
+// process environment, namespace env, script env, injected ARARA_* vars.
+func scriptEnv(cfg *config.DotfilesConfig, script config.Script, dotfilesPath, scriptPath string) []string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	// Injected vars are available for expansion and re-applied last so
+	// they cannot be overridden
+	injected := injectedEnv(dotfilesPath, scriptPath)
+	for k, v := range injected {
+		env[k] = v
+	}
+
+	// Expand values against what has been set so far, so script env can
+	// reference namespace env and both can reference the process env
+	lookup := func(k string) string { return env[k] }
+	merge := func(m map[string]string) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			env[k] = os.Expand(m[k], lookup)
+		}
+	}
+	merge(cfg.Env)
+	merge(script.Env)
+
+	for k, v := range injected {
+		env[k] = v
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, k+"="+env[k])
+	}
+	return out
+}
+
+// privilegeSession returns the run's privilege escalation session, so
+// credentials are validated once for all scripts
+var privilegeSession = privilege.Shared
+
+// injectedEnv returns the well-defined ARARA_* variables every script gets
+func injectedEnv(dotfilesPath, scriptPath string) map[string]string {
+	return map[string]string{
+		"ARARA_NAMESPACE":  bonzaiVars.Fetch(vars.ActiveNamespaceEnv, vars.ActiveNamespaceVar, ""),
+		"ARARA_DOTFILES":   dotfilesPath,
+		"ARARA_SCRIPT":     scriptPath,
+		"ARARA_SCRIPT_DIR": filepath.Dir(scriptPath),
+		"ARARA_OS":         runtime.GOOS,
+		"ARARA_ARCH":       runtime.GOARCH,
+	}
+}
+
+// addedEnv returns the entries of env that differ from the process env
+func addedEnv(env []string) []string {
+	current := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		current[kv] = true
+	}
+	var added []string
+	for _, kv := range env {
+		if !current[kv] {
+			added = append(added, kv)
+		}
+	}
+	return added
+}
+
+// expandArgs expands variable references in default args using env
+func expandArgs(args []string, env []string) []string {
+	values := make(map[string]string, len(env))
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			values[k] = v
+		}
+	}
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		expanded = append(expanded, os.Expand(arg, func(k string) string { return values[k] }))
+	}
+	return expanded
+}
+
+// runScript executes the script at path with args and env, recording the
+// run and its output in the run log under kind (install, uninstall,
+// upgrade). Privileged scripts run through the escalation tool.
+func runScript(kind, name, path string, args []string, env []string, privileged bool) error {
+	// Check if script exists and is executable
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("script not found: %w", err)
+	}
+
+	if info.Mode()&0111 == 0 {
+		return fmt.Errorf("script is not executable: %s", path)
+	}
+
+	cmdArgs := append([]string{path}, args...)
+	if privileged {
+		s, err := privilegeSession()
+		if err != nil {
+			return err
+		}
+		// Escalation resets the environment; pass on what arara adds
+		cmdArgs = s.CommandEnv(addedEnv(env), cmdArgs...)
+	}
+
+	// Execute script
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+
+	// Capture output in the run log; a failing log must not block installs
+	ns := bonzaiVars.Fetch(vars.ActiveNamespaceEnv, vars.ActiveNamespaceVar, "")
+	run, err := runlog.Begin(kind, name, path, ns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: not recording run: %v\n", err)
+	} else {
+		cmd.Stdout = io.MultiWriter(os.Stdout, run.Log())
+		cmd.Stderr = io.MultiWriter(os.Stderr, run.Log())
+	}
+
+	err = cmd.Run()
+
+	if run != nil {
+		if ferr := run.Finish(err); ferr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to record run: %v\n", ferr)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "See 'arara logs %s' for the full output\n", run.ID)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("script execution failed: %w", err)
+	}
+
+	return nil
+}
+
 var executeCmd = &bonzai.Cmd{
 	Name:    "execute",
 	Alias:   "exec",
 	Short:   "execute installation script",
-	Usage:   "execute <script-path>",
-	NumArgs: 1,
-	Vars: bonzai.Vars{
-		{
-			K: "ARARA_SCRIPT",
-			V: "",
-			E: "ARARA_SCRIPT",
-			S: "Path to script being executed",
-		},
-		{
-			K: "ARARA_SCRIPT_DIR",
-			V: "",
-			E: "ARARA_SCRIPT_DIR",
-			S: "Directory containing the script",
-		},
-	},
+	Usage:   "execute <script-path> [args...]",
+	MinArgs: 1,
+	Long: `
+	Execute a script directly by path, passing any remaining arguments.
+	The script receives the current environment plus ARARA_SCRIPT,
+	ARARA_SCRIPT_DIR, ARARA_OS and ARARA_ARCH. A script of the active
+	namespace pinned with sha256 is verified first.
+	`,
 	Do: func(caller *bonzai.Cmd, args ...string) error {
 		path := args[0]
 
-		// Check if script exists and is executable
-		info, err := os.Stat(path)
+		// Scripts of the active namespace stay subject to their pins
+		gc, err := config.NewGlobalConfig()
 		if err != nil {
-			return fmt.Errorf("script not found: %w", err)
+			return fmt.Errorf("failed to load global config: %w", err)
 		}
-
-		if info.Mode()&0111 == 0 {
-			return fmt.Errorf("script is not executable: %s", path)
-		}
-
-		// Build environment with vars.Data
-		env := os.Environ()
-		if data, err := bonzaiVars.Data.All(); err == nil {
-			for _, line := range strings.Split(data, "\n") {
-				if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
-					env = append(env, fmt.Sprintf("%s=%s", parts[0], parts[1]))
-				}
+		if ns := gc.GetActiveNamespace(); ns != nil && ns.Path != "" {
+			cfg, err := config.LoadMergedConfig(filepath.Join(ns.Path, "arara.yaml"))
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := verifyPinnedPath(cfg, ns.Path, path); err != nil {
+				return err
 			}
 		}
 
-		// Execute script
-		cmd := exec.Command(path)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Env = env
-
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("script execution failed: %w", err)
+		env := os.Environ()
+		injected := injectedEnv("", path)
+		for _, k := range []string{"ARARA_SCRIPT", "ARARA_SCRIPT_DIR", "ARARA_OS", "ARARA_ARCH"} {
+			env = append(env, k+"="+injected[k])
 		}
 
-		return nil
+		return runScript("install", filepath.Base(path), path, args[1:], env, false)
 	},
 }
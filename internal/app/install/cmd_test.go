@@ -1,11 +1,17 @@
 package install_test
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/BuddhiLW/arara/internal/app/install"
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/runlog"
+	"github.com/BuddhiLW/arara/internal/pkg/state"
 )
 
 func setupTestEnv(t *testing.T) (string, func()) {
@@ -30,6 +36,24 @@ func setupTestEnv(t *testing.T) (string, func()) {
 		t.Fatal(err)
 	}
 
+	// Create a script that records what it received
+	echoScript := "#!/bin/sh\necho \"$ARARA_NAMESPACE|$ARARA_SCRIPT_DIR|$GREETING|$*\" > \"$ARARA_DOTFILES/out\"\n"
+	if err := os.WriteFile(filepath.Join(scriptsDir, "echo-env"), []byte(echoScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create uninstall and upgrade actions following the naming convention
+	for _, action := range []string{"uninstall", "upgrade"} {
+		dir := filepath.Join(tmpDir, "scripts", action)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := "#!/bin/sh\ntouch \"$ARARA_DOTFILES/" + action + ".done\"\n"
+		if err := os.WriteFile(filepath.Join(dir, "test"), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
 	// Create test arara.yaml
 	if err := os.WriteFile(filepath.Join(tmpDir, "arara.yaml"), []byte(`
 namespace: test
@@ -39,6 +63,31 @@ scripts:
     - name: test
       description: "Test script"
       path: "scripts/install/test-script"
+    - name: incompatible
+      description: "Incompatible script"
+      path: "scripts/install/test-script"
+      compat:
+        os: nonexistent-os
+    - name: pinned-bad
+      description: "Pinned to a different hash"
+      path: "scripts/install/test-script"
+      sha256: "0000000000000000000000000000000000000000000000000000000000000000"
+    - name: echo-env
+      description: "Records its args and env"
+      path: "scripts/install/echo-env"
+      args: ["default-arg"]
+      env:
+        GREETING: "hello $ARARA_OS"
+    - name: as-root
+      description: "Runs through the escalation tool"
+      path: "scripts/install/echo-env"
+      privileged: true
+      env:
+        GREETING: "hello root"
+    - name: pinned-uninstall
+      description: "Uninstalls with a pinned file"
+      path: "scripts/install/echo-env"
+      uninstall: "scripts/install/test-script"
 `), 0644); err != nil {
 		t.Fatal(err)
 	}
@@ -52,15 +101,29 @@ scripts:
 	// Set XDG_CONFIG_HOME to our test config directory
 	origConfigHome := os.Getenv("XDG_CONFIG_HOME")
 	os.Setenv("XDG_CONFIG_HOME", configDir)
+
+	// Keep run logs inside the test directory
+	origStateHome := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", filepath.Join(tmpDir, "state"))
 	
 	// Set up active namespace and test mode
 	os.Setenv("ARARA_ACTIVE_NAMESPACE", "test")
 	os.Setenv("ARARA_DOTFILES_PATH", tmpDir)
 	os.Setenv("TEST_MODE", "1")
 
+	// Register the test namespace so the active namespace resolves
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.AddNamespace("test", tmpDir, ""); err != nil {
+		t.Fatal(err)
+	}
+
 	cleanup := func() {
 		os.RemoveAll(tmpDir)
 		os.Setenv("XDG_CONFIG_HOME", origConfigHome)
+		os.Setenv("XDG_STATE_HOME", origStateHome)
 		os.Unsetenv("ARARA_ACTIVE_NAMESPACE")
 		os.Unsetenv("ARARA_DOTFILES_PATH")
 		os.Unsetenv("TEST_MODE")
@@ -96,6 +159,21 @@ func TestInstallCmd(t *testing.T) {
 			args:    []string{"nonexistent"},
 			wantErr: true,
 		},
+		{
+			name:    "RefuseIncompatibleScript",
+			args:    []string{"incompatible"},
+			wantErr: true,
+		},
+		{
+			name:    "ForceIncompatibleScript",
+			args:    []string{"--force", "incompatible"},
+			wantErr: false,
+		},
+		{
+			name:    "RefuseChecksumMismatch",
+			args:    []string{"--force", "pinned-bad"},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -145,3 +223,360 @@ func TestExecuteCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestPinnedPathIsVerified(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// test-script is pinned by pinned-bad, whichever way it is run
+	pinned := filepath.Join(tmpDir, "scripts", "install", "test-script")
+	if err := install.Cmd.Cmds[1].Do(install.Cmd.Cmds[1], pinned); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("execute error = %v, want checksum mismatch", err)
+	}
+	if err := install.Cmd.Do(install.Cmd, "--uninstall", "pinned-uninstall"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("--uninstall error = %v, want checksum mismatch", err)
+	}
+
+	// Files nothing pins still run
+	if err := install.Cmd.Cmds[1].Do(install.Cmd.Cmds[1], filepath.Join(tmpDir, "scripts", "install", "echo-env")); err != nil {
+		t.Errorf("execute error = %v", err)
+	}
+}
+
+func TestPinnedActions(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	hash := func(path string) string {
+		h, err := runlog.FileHash(filepath.Join(tmpDir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	zeros := strings.Repeat("0", 64)
+
+	// Pin the install script of test, then its actions in turn
+	pin := func(actions map[string]string) {
+		cfg, err := config.ReadConfig(filepath.Join(tmpDir, "arara.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Scripts.Install[0].SHA256 = hash("scripts/install/test-script")
+		cfg.Scripts.Install[0].ActionSHA256 = actions
+		data, err := cfg.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, "arara.yaml"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	upgrade := filepath.Join(tmpDir, "scripts", "upgrade", "test")
+
+	pin(nil)
+	if err := install.Cmd.Do(install.Cmd, "--uninstall", "test"); err == nil || !strings.Contains(err.Error(), "not pinned") {
+		t.Errorf("--uninstall error = %v, want unpinned action refused", err)
+	}
+	if err := install.Cmd.Cmds[1].Do(install.Cmd.Cmds[1], upgrade); err == nil || !strings.Contains(err.Error(), "not pinned") {
+		t.Errorf("execute error = %v, want unpinned action refused", err)
+	}
+
+	pin(map[string]string{"uninstall": zeros, "upgrade": zeros})
+	if err := install.Cmd.Do(install.Cmd, "--uninstall", "test"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("--uninstall error = %v, want checksum mismatch", err)
+	}
+	if err := install.Cmd.Cmds[1].Do(install.Cmd.Cmds[1], upgrade); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("execute error = %v, want checksum mismatch", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "uninstall.done")); err == nil {
+		t.Error("refused uninstall action ran")
+	}
+
+	pin(map[string]string{"uninstall": hash("scripts/uninstall/test"), "upgrade": hash("scripts/upgrade/test")})
+	if err := install.Cmd.Do(install.Cmd, "--uninstall", "test"); err != nil {
+		t.Fatalf("--uninstall error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "uninstall.done")); err != nil {
+		t.Errorf("pinned uninstall action did not run: %v", err)
+	}
+}
+
+func TestScriptDependencies(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// echo-env depends on a package no system has installed
+	cfg, err := config.ReadConfig(filepath.Join(tmpDir, "arara.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range cfg.Scripts.Install {
+		if cfg.Scripts.Install[i].Name == "echo-env" {
+			cfg.Scripts.Install[i].Dependencies = []string{"xbps:arara-test-missing-package"}
+		}
+	}
+	data, err := cfg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "arara.yaml"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(tmpDir, "out")
+	if err := install.Cmd.Do(install.Cmd, "echo-env"); err == nil || !strings.Contains(err.Error(), "missing dependencies") {
+		t.Errorf("install.Cmd.Do() error = %v, want missing dependencies", err)
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("script ran with missing dependencies")
+	}
+
+	if err := install.Cmd.Do(install.Cmd, "--force", "echo-env"); err != nil {
+		t.Fatalf("install.Cmd.Do(--force) error = %v", err)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("script did not run with --force: %v", err)
+	}
+}
+
+func TestScriptArgsAndEnv(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	scriptDir := filepath.Join(tmpDir, "scripts", "install")
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "DefaultArgs",
+			args: []string{"echo-env"},
+			want: "test|" + scriptDir + "|hello " + runtime.GOOS + "|default-arg",
+		},
+		{
+			name: "PassedArgs",
+			args: []string{"echo-env", "--", "one", "two"},
+			want: "test|" + scriptDir + "|hello " + runtime.GOOS + "|one two",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := install.Cmd.Do(install.Cmd, tt.args...); err != nil {
+				t.Fatalf("install.Cmd.Do() error = %v", err)
+			}
+
+			out, err := os.ReadFile(filepath.Join(tmpDir, "out"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(out)); got != tt.want {
+				t.Errorf("script recorded %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrivilegedScript(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// A fake doas that records its arguments and runs the command
+	binDir := filepath.Join(tmpDir, "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	doas := "#!/bin/sh\necho \"$*\" >> \"" + filepath.Join(tmpDir, "doas.log") + "\"\nexec \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "doas"), []byte(doas), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("ARARA_PRIVILEGE", "doas")
+
+	if err := install.Cmd.Do(install.Cmd, "as-root"); err != nil {
+		t.Fatalf("install.Cmd.Do() error = %v", err)
+	}
+
+	out, err := os.ReadFile(filepath.Join(tmpDir, "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptDir := filepath.Join(tmpDir, "scripts", "install")
+	if got, want := strings.TrimSpace(string(out)), "test|"+scriptDir+"|hello root|"; got != want {
+		t.Errorf("script recorded %q, want %q", got, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "doas.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || lines[0] != "true" {
+		t.Fatalf("doas calls = %q, want credential check then script", lines)
+	}
+	if !strings.HasPrefix(lines[1], "env ") || !strings.Contains(lines[1], "GREETING=hello root") ||
+		!strings.HasSuffix(lines[1], filepath.Join(scriptDir, "echo-env")) {
+		t.Errorf("doas ran %q", lines[1])
+	}
+}
+
+func TestInheritedScript(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// A personal namespace layered over the test namespace
+	personal := filepath.Join(tmpDir, "personal")
+	if err := os.MkdirAll(personal, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(personal, "arara.yaml"), []byte("name: personal\nextends: test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.AddNamespace("personal", personal, ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ARARA_ACTIVE_NAMESPACE", "personal")
+	t.Setenv("ARARA_DOTFILES_PATH", personal)
+
+	if err := install.Cmd.Do(install.Cmd, "echo-env"); err != nil {
+		t.Fatalf("install.Cmd.Do() error = %v", err)
+	}
+
+	// The script runs from the base repository
+	out, err := os.ReadFile(filepath.Join(tmpDir, "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptDir := filepath.Join(tmpDir, "scripts", "install")
+	if got, want := strings.TrimSpace(string(out)), "personal|"+scriptDir+"|hello "+runtime.GOOS+"|default-arg"; got != want {
+		t.Errorf("script recorded %q, want %q", got, want)
+	}
+}
+
+func TestRunIsRecorded(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	if err := install.Cmd.Do(install.Cmd, "test"); err != nil {
+		t.Fatalf("install.Cmd.Do() error = %v", err)
+	}
+
+	runs, err := runlog.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("got %d recorded runs, want 1", len(runs))
+	}
+	if runs[0].Script != "test" || runs[0].ExitCode != 0 || runs[0].Hash == "" {
+		t.Errorf("unexpected run metadata: %+v", runs[0])
+	}
+}
+
+func TestUninstallAndUpgrade(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// Nothing installed yet, upgrade is a no-op
+	if err := install.Cmd.Do(install.Cmd, "--upgrade-all"); err != nil {
+		t.Fatalf("--upgrade-all error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "upgrade.done")); err == nil {
+		t.Fatal("upgrade should not run for scripts that are not installed")
+	}
+
+	if err := install.Cmd.Do(install.Cmd, "test"); err != nil {
+		t.Fatalf("install error = %v", err)
+	}
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.IsInstalled("test", "test") {
+		t.Fatal("test script should be recorded as installed")
+	}
+
+	if err := install.Cmd.Do(install.Cmd, "--upgrade-all"); err != nil {
+		t.Fatalf("--upgrade-all error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "upgrade.done")); err != nil {
+		t.Error("upgrade action was not run")
+	}
+
+	if err := install.Cmd.Do(install.Cmd, "--uninstall", "test"); err != nil {
+		t.Fatalf("--uninstall error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "uninstall.done")); err != nil {
+		t.Error("uninstall action was not run")
+	}
+	st, err = state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.IsInstalled("test", "test") {
+		t.Error("test script should no longer be recorded as installed")
+	}
+
+	// Scripts without an uninstall action are refused
+	if err := install.Cmd.Do(install.Cmd, "--uninstall", "echo-env"); err == nil {
+		t.Error("--uninstall should fail without an uninstall script")
+	}
+}
+
+func TestPick(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	oldStdin, oldStdout := install.Stdin, install.Stdout
+	defer func() {
+		install.Stdin, install.Stdout = oldStdin, oldStdout
+	}()
+
+	tests := []struct {
+		name          string
+		input         string
+		wantInstalled []string
+	}{
+		{
+			name:  "Quit",
+			input: "q\n",
+		},
+		{
+			name:  "Decline",
+			input: "1\n\nn\n",
+		},
+		{
+			// Toggling the incompatible script is refused without --force
+			name:          "SelectAndConfirm",
+			input:         "2\n1 4\n\ny\n",
+			wantInstalled: []string{"echo-env", "test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			install.Stdin = strings.NewReader(tt.input)
+			install.Stdout = out
+
+			if err := install.Cmd.Do(install.Cmd, "--pick"); err != nil {
+				t.Fatalf("install --pick error = %v\n%s", err, out)
+			}
+
+			st, err := state.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := st.InstalledScripts("test")
+			if strings.Join(got, ",") != strings.Join(tt.wantInstalled, ",") {
+				t.Errorf("installed = %v, want %v\n%s", got, tt.wantInstalled, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package install
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BuddhiLW/arara/internal/app/compat"
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/state"
+)
+
+// Add to package-level vars for testing
+var (
+	Stdin  io.Reader = os.Stdin  // For mocking in tests
+	Stdout io.Writer = os.Stdout // For capturing output
+)
+
+// pickItem is a script offered by the picker
+type pickItem struct {
+	script   config.Script
+	failures []compat.Failure
+	selected bool
+}
+
+// pickScripts lets the user toggle several scripts, confirms the selection
+// and installs the chosen scripts in the order they appear in arara.yaml
+func pickScripts(cfg *config.DotfilesConfig, st *state.State, ns, dotfilesPath string, force bool) error {
+	if len(cfg.Scripts.Install) == 0 {
+		fmt.Fprintln(Stdout, "No installation scripts defined")
+		return nil
+	}
+
+	items := make([]*pickItem, 0, len(cfg.Scripts.Install))
+	for _, script := range cfg.Scripts.Install {
+		items = append(items, &pickItem{
+			script:   script,
+			failures: compat.Failures(compat.FromConfig(script.Compat)),
+		})
+	}
+
+	scanner := bufio.NewScanner(Stdin)
+	if !selectItems(scanner, items, st, ns, force) {
+		fmt.Fprintln(Stdout, "Cancelled")
+		return nil
+	}
+
+	var chosen []config.Script
+	for _, item := range items {
+		if item.selected {
+			chosen = append(chosen, item.script)
+		}
+	}
+	if len(chosen) == 0 {
+		fmt.Fprintln(Stdout, "Nothing selected")
+		return nil
+	}
+
+	// Confirmation summary
+	fmt.Fprintf(Stdout, "\nAbout to install %d scripts:\n", len(chosen))
+	for i, script := range chosen {
+		fmt.Fprintf(Stdout, "  %d. %s - %s\n", i+1, script.Name, script.Description)
+	}
+	fmt.Fprint(Stdout, "Proceed? [y/N] ")
+	if !scanner.Scan() {
+		return scanner.Err()
+	}
+	if answer := strings.ToLower(strings.TrimSpace(scanner.Text())); answer != "y" && answer != "yes" {
+		fmt.Fprintln(Stdout, "Cancelled")
+		return nil
+	}
+
+	return runPicked(cfg, st, ns, dotfilesPath, chosen, force)
+}
+
+// selectItems shows the toggle list until the user confirms with an empty
+// line. Returns false if the user quit.
+func selectItems(scanner *bufio.Scanner, items []*pickItem, st *state.State, ns string, force bool) bool {
+	width := len(fmt.Sprint(len(items)))
+	for {
+		fmt.Fprintln(Stdout, "\nSelect scripts to install:")
+		for i, item := range items {
+			mark := " "
+			if item.selected {
+				mark = "x"
+			}
+			status := "✓"
+			if len(item.failures) > 0 {
+				status = "✗"
+			}
+			line := fmt.Sprintf("%*d. [%s] %s %s - %s", width, i+1, mark, status, item.script.Name, item.script.Description)
+			if st.IsInstalled(ns, item.script.Name) {
+				line += " [installed]"
+			}
+			if len(item.failures) > 0 {
+				line += fmt.Sprintf(" (%s)", item.failures[0])
+			}
+			fmt.Fprintln(Stdout, line)
+		}
+		fmt.Fprint(Stdout, "Toggle numbers (e.g. 1 3), a=all, n=none, Enter=continue, q=quit: ")
+
+		if !scanner.Scan() {
+			return false
+		}
+		resp := strings.TrimSpace(scanner.Text())
+
+		switch resp {
+		case "":
+			return true
+		case "q":
+			return false
+		case "a":
+			for _, item := range items {
+				if len(item.failures) == 0 || force {
+					item.selected = true
+				}
+			}
+			continue
+		case "n":
+			for _, item := range items {
+				item.selected = false
+			}
+			continue
+		}
+
+		for _, field := range strings.FieldsFunc(resp, func(r rune) bool { return r == ' ' || r == ',' }) {
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 1 || n > len(items) {
+				fmt.Fprintf(Stdout, "Ignoring invalid choice: %s\n", field)
+				continue
+			}
+			item := items[n-1]
+			if len(item.failures) > 0 && !force && !item.selected {
+				fmt.Fprintf(Stdout, "%s is not compatible with this system (use --force)\n", item.script.Name)
+				continue
+			}
+			item.selected = !item.selected
+		}
+	}
+}
+
+// runPicked installs scripts in order, printing progress before each
+// script and a result summary at the end
+func runPicked(cfg *config.DotfilesConfig, st *state.State, ns, dotfilesPath string, scripts []config.Script, force bool) error {
+	type result struct {
+		name    string
+		err     error
+		elapsed time.Duration
+	}
+	results := make([]result, 0, len(scripts))
+
+	for i, script := range scripts {
+		fmt.Fprintf(Stdout, "\n[%d/%d] Installing %s...\n", i+1, len(scripts), script.Name)
+		start := time.Now()
+		err := checkCompat(script, force)
+		if err == nil {
+			err = checkDependencies(script, force)
+		}
+		if err == nil {
+			err = installScript(cfg, st, ns, dotfilesPath, script, nil)
+		}
+		results = append(results, result{script.Name, err, time.Since(start)})
+
+		if err != nil {
+			fmt.Fprintf(Stdout, "[%d/%d] ✗ %s failed: %v\n", i+1, len(scripts), script.Name, err)
+		} else {
+			fmt.Fprintf(Stdout, "[%d/%d] ✓ %s done\n", i+1, len(scripts), script.Name)
+		}
+	}
+
+	fmt.Fprintln(Stdout, "\nSummary:")
+	var failed []string
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, r.name)
+			fmt.Fprintf(Stdout, "  ✗ %s (%s)\n", r.name, r.elapsed.Round(time.Millisecond))
+		} else {
+			fmt.Fprintf(Stdout, "  ✓ %s (%s)\n", r.name, r.elapsed.Round(time.Millisecond))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to install: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -1,13 +1,18 @@
 package link
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/rwxrob/bonzai"
 	"github.com/rwxrob/bonzai/cmds/help"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
 )
 
 // shouldRemoveExisting checks if dst exists, is non-empty, and if a backup directory
@@ -44,69 +49,106 @@ func shouldRemoveExisting(dst, home string) bool {
 	return false
 }
 
+// symlink is a link to create, dst pointing to src
+type symlink struct {
+	src string
+	dst string
+}
+
+// defaultLinks are created when arara.yaml declares no links
+func defaultLinks(home, dotfiles string) (core, configs []symlink) {
+	core = []symlink{
+		{filepath.Join(dotfiles, ".config"), filepath.Join(home, ".config")},
+		{filepath.Join(dotfiles, ".local"), filepath.Join(home, ".local")},
+	}
+	configs = []symlink{
+		{filepath.Join(dotfiles, ".bashrc"), filepath.Join(home, ".bashrc")},
+		{filepath.Join(dotfiles, ".vim"), filepath.Join(home, ".vim")},
+		{filepath.Join(dotfiles, ".doom.d"), filepath.Join(home, ".doom.d")},
+		{filepath.Join(dotfiles, ".config/tmux/.tmux.conf"), filepath.Join(home, ".tmux.conf")},
+		{filepath.Join(dotfiles, ".config/vim/.vimrc"), filepath.Join(home, ".vimrc")},
+		{filepath.Join(dotfiles, ".config/X11/xinitrc"), filepath.Join(home, ".xinitrc")},
+	}
+	return core, configs
+}
+
+// configuredLinks expands the links of arara.yaml, $DOTFILES referring
+// to dotfiles
+func configuredLinks(links []config.Link, dotfiles string) []symlink {
+	out := make([]symlink, len(links))
+	for i, l := range links {
+		l = l.Expand(dotfiles)
+		out[i] = symlink{l.Source, l.Target}
+	}
+	return out
+}
+
 var Cmd = &bonzai.Cmd{
 	Name:  "link",
 	Alias: "ln",
 	Short: "create symlinks for dotfiles",
-	Cmds:  []*bonzai.Cmd{help.Cmd},
+	Long: `
+Create the symlinks declared in the setup section of the arara.yaml in
+$DOTFILES (default ~/dotfiles): core_links replace directories that were
+backed up, config_links replace existing files. Links of the namespace it
+extends are included, local links win for the same target. Without any
+declared links, the common .config, .local and shell/editor files are
+linked.
+`,
+	Cmds: []*bonzai.Cmd{help.Cmd},
 	Do: func(caller *bonzai.Cmd, args ...string) error {
-		home := os.Getenv("HOME")
 		dotfiles := os.Getenv("DOTFILES")
 		if dotfiles == "" {
-			dotfiles = filepath.Join(home, "dotfiles")
+			dotfiles = filepath.Join(os.Getenv("HOME"), "dotfiles")
 		}
+		return Run(os.Stdout, dotfiles)
+	},
+}
 
-		// Core directory links
-		coreLinks := []struct {
-			src string
-			dst string
-		}{
-			{filepath.Join(dotfiles, ".config"), filepath.Join(home, ".config")},
-			{filepath.Join(dotfiles, ".local"), filepath.Join(home, ".local")},
-		}
+// Run creates the links of the arara.yaml in dotfiles, writing progress
+// to w
+func Run(w io.Writer, dotfiles string) error {
+	home := os.Getenv("HOME")
+	coreLinks, configLinks := defaultLinks(home, dotfiles)
+	cfg, err := config.LoadMergedConfig(filepath.Join(dotfiles, "arara.yaml"))
+	switch {
+	case err == nil && len(cfg.Setup.CoreLinks)+len(cfg.Setup.ConfigLinks) > 0:
+		coreLinks = configuredLinks(cfg.Setup.CoreLinks, dotfiles)
+		configLinks = configuredLinks(cfg.Setup.ConfigLinks, dotfiles)
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("failed to load config: %w", err)
+	}
 
-		for _, link := range coreLinks {
-			// if destination exists and is non-empty, and a backup exists, remove it first
-			if _, err := os.Lstat(link.dst); err == nil {
-				if shouldRemoveExisting(link.dst, home) {
-					if err := os.RemoveAll(link.dst); err != nil {
-						return fmt.Errorf("failed to remove existing directory %s: %w", link.dst, err)
-					}
+	// Core directory links
+	for _, link := range coreLinks {
+		// if destination exists and is non-empty, and a backup exists, remove it first
+		if _, err := os.Lstat(link.dst); err == nil {
+			if shouldRemoveExisting(link.dst, home) {
+				if err := os.RemoveAll(link.dst); err != nil {
+					return fmt.Errorf("failed to remove existing directory %s: %w", link.dst, err)
 				}
 			}
-
-			if err := os.Symlink(link.src, link.dst); err != nil {
-				return fmt.Errorf("failed to create link %s -> %s: %w", link.src, link.dst, err)
-			}
-			fmt.Printf("Created symlink: %s -> %s\n", link.dst, link.src)
 		}
 
-		// Config file links
-		configLinks := []struct {
-			src string
-			dst string
-		}{
-			{filepath.Join(dotfiles, ".bashrc"), filepath.Join(home, ".bashrc")},
-			{filepath.Join(dotfiles, ".vim"), filepath.Join(home, ".vim")},
-			{filepath.Join(dotfiles, ".doom.d"), filepath.Join(home, ".doom.d")},
-			{filepath.Join(dotfiles, ".config/tmux/.tmux.conf"), filepath.Join(home, ".tmux.conf")},
-			{filepath.Join(dotfiles, ".config/vim/.vimrc"), filepath.Join(home, ".vimrc")},
-			{filepath.Join(dotfiles, ".config/X11/xinitrc"), filepath.Join(home, ".xinitrc")},
+		if err := os.Symlink(link.src, link.dst); err != nil {
+			return fmt.Errorf("failed to create link %s -> %s: %w", link.src, link.dst, err)
 		}
+		fmt.Fprintf(w, "Created symlink: %s -> %s\n", link.dst, link.src)
+	}
 
-		for _, link := range configLinks {
-			// For config links, if a file or symlink already exists, remove it.
-			if _, err := os.Lstat(link.dst); err == nil {
-				if err := os.RemoveAll(link.dst); err != nil {
-					return fmt.Errorf("failed to remove existing file/directory %s: %w", link.dst, err)
-				}
-			}
-			if err := os.Symlink(link.src, link.dst); err != nil {
-				return fmt.Errorf("failed to create link %s -> %s: %w", link.src, link.dst, err)
+	// Config file links
+	for _, link := range configLinks {
+		// For config links, if a file or symlink already exists, remove it.
+		if _, err := os.Lstat(link.dst); err == nil {
+			if err := os.RemoveAll(link.dst); err != nil {
+				return fmt.Errorf("failed to remove existing file/directory %s: %w", link.dst, err)
 			}
-			fmt.Printf("Created symlink: %s -> %s\n", link.dst, link.src)
 		}
+		if err := os.Symlink(link.src, link.dst); err != nil {
+			return fmt.Errorf("failed to create link %s -> %s: %w", link.src, link.dst, err)
+		}
+		fmt.Fprintf(w, "Created symlink: %s -> %s\n", link.dst, link.src)
+	}
 
-		return nil
-	},
+	return nil
 }
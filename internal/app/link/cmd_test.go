@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
 )
 
 func TestLinkCmd(t *testing.T) {
@@ -154,4 +156,65 @@ func verifySymlink(t *testing.T, src, dst string) {
 	if linkDest != src {
 		t.Errorf("Symlink %s points to %s, expected %s", dst, linkDest, src)
 	}
-}
\ No newline at end of file
+}
+func TestLinkCmd_ConfiguredLinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	homeDir := filepath.Join(tmpDir, "home")
+	team := filepath.Join(tmpDir, "team")
+	dotfilesDir := filepath.Join(tmpDir, "dotfiles")
+	t.Setenv("HOME", homeDir)
+	t.Setenv("DOTFILES", dotfilesDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+
+	// The team repository links .gitconfig and .bashrc; the personal one
+	// extends it and links its own .bashrc instead
+	files := map[string]string{
+		filepath.Join(team, "arara.yaml"): `
+setup:
+  config_links:
+    - source: "$DOTFILES/.gitconfig"
+      target: "$HOME/.gitconfig"
+    - source: "$DOTFILES/.bashrc"
+      target: "$HOME/.bashrc"
+`,
+		filepath.Join(dotfilesDir, "arara.yaml"): `
+extends: team
+setup:
+  config_links:
+    - source: "$DOTFILES/.bashrc"
+      target: "$HOME/.bashrc"
+`,
+		filepath.Join(team, ".gitconfig"):     "[user]",
+		filepath.Join(team, ".bashrc"):        "# team",
+		filepath.Join(dotfilesDir, ".bashrc"): "# personal",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(homeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.AddNamespace("team", team, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Cmd.Do(Cmd); err != nil {
+		t.Fatalf("Failed to execute link command: %v", err)
+	}
+
+	verifySymlink(t, filepath.Join(team, ".gitconfig"), filepath.Join(homeDir, ".gitconfig"))
+	verifySymlink(t, filepath.Join(dotfilesDir, ".bashrc"), filepath.Join(homeDir, ".bashrc"))
+	if _, err := os.Lstat(filepath.Join(homeDir, ".config")); !os.IsNotExist(err) {
+		t.Errorf("default links should not be created when links are declared")
+	}
+}
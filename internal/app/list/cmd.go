@@ -66,6 +66,18 @@ func autoAddNamespace(path string) error {
 	return nil
 }
 
+// printScripts lists the install scripts of cfg, marking those inherited
+// from the namespace it extends
+func printScripts(cfg *config.DotfilesConfig) {
+	for _, script := range cfg.Scripts.Install {
+		if script.Dir != "" {
+			fmt.Printf("  %s - %s (inherited)\n", script.Name, script.Description)
+			continue
+		}
+		fmt.Printf("  %s - %s\n", script.Name, script.Description)
+	}
+}
+
 var Cmd = &bonzai.Cmd{
 	Name:  "list",
 	Alias: "l",
@@ -73,6 +85,7 @@ var Cmd = &bonzai.Cmd{
 	Long: `
 List available installation scripts defined in arara.yaml.
 By default, tries to use local arara.yaml if present, otherwise uses active namespace.
+Scripts of the namespace it extends are included and marked (inherited).
 
 Commands:
   local   - Force list from current directory's arara.yaml
@@ -92,15 +105,13 @@ Commands:
 					return err
 				}
 				// List scripts from local config
-				cfg, err := config.LoadConfig("arara.yaml")
+				cfg, err := config.LoadMergedConfig("arara.yaml")
 				if err != nil {
 					return fmt.Errorf("failed to load local config: %w", err)
 				}
 
 				fmt.Println("Available installation scripts (local):")
-				for _, script := range cfg.Scripts.Install {
-					fmt.Printf("  %s - %s\n", script.Name, script.Description)
-				}
+				printScripts(cfg)
 				return nil
 			}
 		}
@@ -113,15 +124,13 @@ var localCmd = &bonzai.Cmd{
 	Name:  "local",
 	Short: "list scripts from local arara.yaml",
 	Do: func(caller *bonzai.Cmd, args ...string) error {
-		cfg, err := config.LoadConfig("arara.yaml")
+		cfg, err := config.LoadMergedConfig("arara.yaml")
 		if err != nil {
 			return fmt.Errorf("failed to load local config: %w", err)
 		}
 
 		fmt.Println("Available installation scripts (local):")
-		for _, script := range cfg.Scripts.Install {
-			fmt.Printf("  %s - %s\n", script.Name, script.Description)
-		}
+		printScripts(cfg)
 		return nil
 	},
 }
@@ -145,15 +154,13 @@ var globalCmd = &bonzai.Cmd{
 			return fmt.Errorf("no dotfiles path found for namespace: %s", activeNS)
 		}
 
-		cfg, err := config.LoadConfig(filepath.Join(dotfilesPath, "arara.yaml"))
+		cfg, err := config.LoadMergedConfig(filepath.Join(dotfilesPath, "arara.yaml"))
 		if err != nil {
 			return fmt.Errorf("failed to load config for namespace %s: %w", activeNS, err)
 		}
 
 		fmt.Printf("Available installation scripts (namespace: %s):\n", activeNS)
-		for _, script := range cfg.Scripts.Install {
-			fmt.Printf("  %s - %s\n", script.Name, script.Description)
-		}
+		printScripts(cfg)
 		return nil
 	},
 }
@@ -0,0 +1,69 @@
+package logs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rwxrob/bonzai"
+	"github.com/rwxrob/bonzai/cmds/help"
+
+	"github.com/BuddhiLW/arara/internal/pkg/runlog"
+)
+
+var Cmd = &bonzai.Cmd{
+	Name:    "logs",
+	Alias:   "log",
+	Short:   "show the captured output of a run",
+	Usage:   "logs <run-id>",
+	NumArgs: 1,
+	Long: `
+Show the metadata and captured stdout/stderr of a recorded run.
+Run IDs are listed by 'arara history'.
+
+Example:
+  arara logs 20250328-141503-docker
+`,
+	Cmds: []*bonzai.Cmd{help.Cmd},
+	Do: func(caller *bonzai.Cmd, args ...string) error {
+		run, err := runlog.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Run:       %s\n", run.ID)
+		fmt.Printf("Kind:      %s\n", run.Kind)
+		fmt.Printf("Script:    %s\n", run.Script)
+		if run.Path != "" {
+			fmt.Printf("Path:      %s\n", run.Path)
+		}
+		if run.Hash != "" {
+			fmt.Printf("SHA-256:   %s\n", run.Hash)
+		}
+		if run.Namespace != "" {
+			fmt.Printf("Namespace: %s\n", run.Namespace)
+		}
+		fmt.Printf("Host:      %s\n", run.Host)
+		fmt.Printf("Start:     %s\n", run.Start.Format(time.RFC3339))
+		fmt.Printf("End:       %s\n", run.End.Format(time.RFC3339))
+		fmt.Printf("Exit code: %d\n", run.ExitCode)
+		if run.Error != "" {
+			fmt.Printf("Error:     %s\n", run.Error)
+		}
+		fmt.Println("----")
+
+		logPath, err := runlog.LogPath(run.ID)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(logPath)
+		if err != nil {
+			return fmt.Errorf("failed to open run log: %w", err)
+		}
+		defer f.Close()
+
+		_, err = io.Copy(os.Stdout, f)
+		return err
+	},
+}
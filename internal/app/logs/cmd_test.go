@@ -0,0 +1,38 @@
+package logs_test
+
+import (
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/app/logs"
+	"github.com/BuddhiLW/arara/internal/pkg/runlog"
+)
+
+func TestLogsCmd(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	run, err := runlog.Begin("install", "docker", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := run.Finish(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		wantErr bool
+	}{
+		{name: "ExistingRun", id: run.ID},
+		{name: "UnknownRun", id: "nonexistent", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := logs.Cmd.Do(logs.Cmd, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("logs.Cmd.Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
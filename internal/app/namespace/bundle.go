@@ -0,0 +1,91 @@
+package namespace
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+// bundle is the portable form of the namespace registry written by
+// 'namespace export' and read by 'namespace import'
+type bundle struct {
+	Namespaces []bundleEntry `yaml:"namespaces"`
+}
+
+// bundleEntry describes a namespace independently of the machine
+type bundleEntry struct {
+	Name       string   `yaml:"name"`
+	Path       string   `yaml:"path"`             // ~/ for paths under the home directory
+	Remote     string   `yaml:"remote,omitempty"` // git remote to clone from when the path is missing
+	LocalBin   string   `yaml:"local-bin,omitempty"`
+	BackupDirs []string `yaml:"backup_dirs,omitempty"`
+}
+
+// newBundle describes the registered namespaces of gc
+func newBundle(gc *config.GlobalConfig) bundle {
+	var b bundle
+	for _, ns := range gc.Namespaces {
+		info := gc.Configs[ns]
+		b.Namespaces = append(b.Namespaces, bundleEntry{
+			Name:       ns,
+			Path:       portablePath(info.Path),
+			Remote:     gitRemote(info.Path),
+			LocalBin:   info.LocalBin,
+			BackupDirs: info.Dirs,
+		})
+	}
+	return b
+}
+
+// gitRemote returns the URL of the origin remote of the repository at
+// dir, or the first remote if there is no origin
+func gitRemote(dir string) string {
+	if out, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output(); err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	out, err := exec.Command("git", "-C", dir, "remote").Output()
+	if err != nil {
+		return ""
+	}
+	remotes := strings.Fields(string(out))
+	if len(remotes) == 0 {
+		return ""
+	}
+	out, err = exec.Command("git", "-C", dir, "remote", "get-url", remotes[0]).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// portablePath writes path relative to the home directory as ~/...
+func portablePath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(home, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return "~/" + filepath.ToSlash(rel)
+}
+
+// localPath resolves a path written by portablePath on this machine
+func localPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		return filepath.Join(home, filepath.FromSlash(strings.TrimPrefix(path, "~"))), nil
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("path must be absolute or start with ~/: %s", path)
+	}
+	return path, nil
+}
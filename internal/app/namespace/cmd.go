@@ -1,16 +1,25 @@
 package namespace
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/rwxrob/bonzai"
 	"github.com/rwxrob/bonzai/cmds/help"
 	"github.com/rwxrob/bonzai/edit"
 	"github.com/rwxrob/bonzai/vars"
+	"gopkg.in/yaml.v3"
 
+	"github.com/BuddhiLW/arara/internal/app/backup"
+	"github.com/BuddhiLW/arara/internal/app/link"
 	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/state"
 	v "github.com/BuddhiLW/arara/internal/pkg/vars"
 )
 
@@ -26,6 +35,13 @@ var Cmd = &bonzai.Cmd{
 		editCmd,
 		addCmd,
 		removeCmd,
+		currentCmd,
+		cloneCmd,
+		renameCmd,
+		infoCmd,
+		doctorCmd,
+		exportCmd,
+		importCmd,
 	},
 }
 
@@ -77,6 +93,12 @@ var switchCmd = &bonzai.Cmd{
 
 		fmt.Printf("Switched to namespace: %s\n", ns)
 		fmt.Printf("Dotfiles path: %s\n", info.Path)
+
+		// The switch is overridden where another namespace applies
+		cwd, _ := os.Getwd()
+		if res := gc.ActiveResolution(cwd); res.Name != ns && (res.Source == config.FromEnv || res.Source == config.FromDirectory) {
+			fmt.Printf("Note: %s stays active here (%s)\n", res.Name, res.Explain())
+		}
 		return nil
 	},
 }
@@ -235,3 +257,629 @@ Example:
 		return nil
 	},
 }
+
+var currentCmd = &bonzai.Cmd{
+	Name:  "current",
+	Alias: "cur",
+	Short: "show the active namespace and why",
+	Usage: "current [--short]",
+	Long: `
+Show the active namespace, its dotfiles path and where the choice came
+from. The active namespace is, in order of precedence:
+
+  env        the ARARA_ACTIVE_NAMESPACE environment variable
+  directory  the registered namespace whose arara.yaml is found walking
+             up from the current directory, like git finds .git
+  switch     the namespace chosen with 'arara namespace switch'
+  default    the first registered namespace
+
+Inside a registered dotfiles repository every command uses its namespace
+without switching.
+
+With --short only the name is printed, or nothing without an active
+namespace, for use in shell prompts (see 'arara shell-init').
+`,
+	Do: func(x *bonzai.Cmd, args ...string) error {
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			return err
+		}
+
+		cwd, _ := os.Getwd()
+		res := gc.ActiveResolution(cwd)
+		if len(args) > 0 && args[0] == "--short" {
+			if res.Name != "" {
+				fmt.Println(res.Name)
+			}
+			return nil
+		}
+		if res.Name == "" {
+			fmt.Println("No active namespace. Use 'arara namespace add <name> <path>' first")
+			return nil
+		}
+
+		fmt.Println(res.Name)
+		if res.Path != "" {
+			fmt.Printf("  path:   %s\n", res.Path)
+		} else {
+			fmt.Printf("  path:   unknown, %s is not registered\n", res.Name)
+		}
+		fmt.Printf("  source: %s (%s)\n", res.Source, res.Explain())
+		if res.ConfigPath != "" && res.Source != config.FromDirectory {
+			if _, ok := gc.NamespaceAt(filepath.Dir(res.ConfigPath)); !ok {
+				fmt.Printf("  note:   %s is not a registered namespace, see 'arara namespace add'\n", res.ConfigPath)
+			}
+		}
+		return nil
+	},
+}
+
+var cloneCmd = &bonzai.Cmd{
+	Name:    "clone",
+	Alias:   "cl",
+	Short:   "clone a namespace from a git repository",
+	Usage:   "clone [--setup] <name> <git-url> [path]",
+	MinArgs: 2,
+	Long: `
+Clone a dotfiles repository and add it as a namespace in one step.
+
+The repository is cloned with git, so any remote git understands works,
+including local paths and file:// URLs. It must contain an arara.yaml at
+its root; if it doesn't, the file can't be parsed, or it declares
+another namespace than name that is not registered, the clone is
+removed again and nothing is registered.
+
+Arguments:
+  name: Name of the namespace
+  git-url: Repository to clone
+  path: Where to clone it, defaults to
+        $XDG_DATA_HOME/arara/namespaces/<name>
+
+Options:
+  --setup: Back up existing dotfiles and create the symlinks afterwards,
+           like 'arara setup backup' and 'arara setup link'
+
+Example:
+  arara namespace clone work git@github.com:me/work-dotfiles.git
+  arara namespace clone --setup personal https://github.com/me/dotfiles ~/dotfiles
+`,
+	Do: func(x *bonzai.Cmd, args ...string) error {
+		var setup bool
+		var rest []string
+		for _, arg := range args {
+			if arg == "--setup" {
+				setup = true
+				continue
+			}
+			rest = append(rest, arg)
+		}
+		if len(rest) < 2 || len(rest) > 3 {
+			return fmt.Errorf("usage: arara namespace clone [--setup] <name> <git-url> [path]")
+		}
+		name, url := rest[0], rest[1]
+
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			return err
+		}
+		if _, ok := gc.Config.Configs[name]; ok {
+			return fmt.Errorf("namespace already exists: %s", name)
+		}
+
+		path := namespacesDir(name)
+		if len(rest) == 3 {
+			path = rest[2]
+		}
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+		if entries, err := os.ReadDir(path); err == nil && len(entries) > 0 {
+			return fmt.Errorf("destination %s already exists and is not empty", path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+
+		if _, err := cloneRepo(gc, name, url, path); err != nil {
+			return err
+		}
+
+		if err := gc.AddNamespace(name, path, ""); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("Added namespace '%s' pointing to %s\n", name, path)
+
+		if !setup {
+			fmt.Printf("Run 'arara namespace switch %s' and 'arara build' to set it up\n", name)
+			return nil
+		}
+		if err := backup.Run(os.Stdout, path); err != nil {
+			return fmt.Errorf("failed to back up dotfiles: %w", err)
+		}
+		if err := link.Run(os.Stdout, path); err != nil {
+			return fmt.Errorf("failed to create symlinks: %w", err)
+		}
+		return nil
+	},
+}
+
+// cloneRepo clones the dotfiles repository at url into path for namespace
+// name and loads its arara.yaml. A repository without a valid arara.yaml,
+// or whose arara.yaml declares a namespace that is neither name nor
+// registered in gc, is removed again.
+func cloneRepo(gc *config.GlobalConfig, name, url, path string) (*config.DotfilesConfig, error) {
+	fmt.Printf("Cloning %s into %s...\n", url, path)
+	clone := exec.Command("git", "clone", url, path)
+	clone.Stdout = os.Stdout
+	clone.Stderr = os.Stderr
+	if err := clone.Run(); err != nil {
+		return nil, fmt.Errorf("failed to clone %s: %w", url, err)
+	}
+
+	// Only register repositories arara can use
+	cfg, err := config.ReadConfig(filepath.Join(path, "arara.yaml"))
+	if err != nil {
+		os.RemoveAll(path)
+		return nil, fmt.Errorf("%s is not a dotfiles repository: %w", url, err)
+	}
+
+	// Loading it would fail with an undefined namespace from then on
+	if ns := cfg.Namespace; ns != "" && ns != name && !contains(gc.Config.Namespaces, ns) {
+		os.RemoveAll(path)
+		return nil, fmt.Errorf("arara.yaml of %s declares namespace '%s'; clone it as '%s' or edit its namespace field", url, ns, ns)
+	}
+	return cfg, nil
+}
+
+// namespacesDir is where cloned namespaces go by default
+func namespacesDir(name string) string {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, _ := os.UserHomeDir()
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, "arara", "namespaces", name)
+}
+
+var renameCmd = &bonzai.Cmd{
+	Name:    "rename",
+	Alias:   "mv",
+	Short:   "rename a namespace",
+	Usage:   "rename <old> <new>",
+	NumArgs: 2,
+	Long: `
+Rename a namespace in the global configuration. The namespace keeps its
+position, path and settings, stays active if it was, and keeps the
+scripts and packages recorded as installed for it.
+
+The namespace field of the renamed namespace's own arara.yaml is updated
+too, since the file would no longer load. If any step fails, everything
+is put back. Other registered repositories whose arara.yaml names the old
+namespace, such as one extending it, are listed but left alone: they may
+be shared with others still using the old name, so edit them yourself.
+
+Example:
+  arara namespace rename work acme
+`,
+	Do: func(x *bonzai.Cmd, args ...string) error {
+		old, name := args[0], args[1]
+
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			return err
+		}
+
+		idx := -1
+		for i, ns := range gc.Config.Namespaces {
+			if ns == old {
+				idx = i
+			}
+			if ns == name {
+				return fmt.Errorf("namespace already exists: %s", name)
+			}
+		}
+		if idx == -1 {
+			return fmt.Errorf("namespace not found: %s", old)
+		}
+
+		// An arara.yaml naming the old namespace would fail to load
+		originals, stale, err := renameReferences(gc, old, name)
+		if err != nil {
+			return err
+		}
+		restoreFiles := func() {
+			for path, data := range originals {
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					fmt.Fprintf(os.Stderr, "warning: failed to restore %s: %v\n", path, err)
+				}
+			}
+		}
+
+		// Keep the previous config to undo the rename if the active
+		// namespace can't follow it
+		prev := gc.Config
+		prev.Namespaces = append([]string{}, gc.Config.Namespaces...)
+		prev.Configs = make(map[string]config.NSInfo)
+		for ns, info := range gc.Config.Configs {
+			prev.Configs[ns] = info
+		}
+
+		gc.Config.Namespaces[idx] = name
+		gc.Config.Configs[name] = gc.Config.Configs[old]
+		delete(gc.Config.Configs, old)
+		if err := gc.Save(); err != nil {
+			restoreFiles()
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		if active, err := vars.Data.Get(v.ActiveNamespaceVar); err == nil && active == old {
+			if err := vars.Data.Set(v.ActiveNamespaceVar, name); err != nil {
+				restoreFiles()
+				gc.Config = prev
+				if serr := gc.Save(); serr != nil {
+					return fmt.Errorf("failed to update active namespace: %w (and to restore config: %v)", err, serr)
+				}
+				return fmt.Errorf("failed to update active namespace: %w", err)
+			}
+		}
+
+		st, err := state.Load()
+		if err == nil {
+			st.RenameNamespace(old, name)
+			err = st.Save()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: installed scripts and packages are still recorded under %s: %v\n", old, err)
+		}
+
+		fmt.Printf("Renamed namespace '%s' to '%s'\n", old, name)
+		if os.Getenv(v.ActiveNamespaceEnv) == old {
+			fmt.Printf("Note: $%s still names %s\n", v.ActiveNamespaceEnv, old)
+		}
+		for path := range originals {
+			fmt.Printf("Updated references to '%s' in %s\n", old, path)
+		}
+		for _, path := range stale {
+			fmt.Printf("Note: %s still refers to '%s'; change it to '%s' there\n", path, old, name)
+		}
+		return nil
+	},
+}
+
+// renameReferences rewrites the namespace and extends fields naming old
+// in the arara.yaml of the renamed namespace to name, returning its
+// original content if it changed. The arara.yaml files of other
+// registered namespaces naming old are only returned in stale, since
+// they may be shared repositories.
+func renameReferences(gc *config.GlobalConfig, old, name string) (map[string][]byte, []string, error) {
+	field := regexp.MustCompile(`(?m)^((?:namespace|extends):[ \t]*["']?)` + regexp.QuoteMeta(old) + `(["']?[ \t]*(?:#.*)?)$`)
+
+	own := filepath.Join(gc.Config.Configs[old].Path, "arara.yaml")
+	var stale []string
+	for _, ns := range gc.Config.Namespaces {
+		path := filepath.Join(gc.Config.Configs[ns].Path, "arara.yaml")
+		if path == own || slices.Contains(stale, path) {
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil && field.Match(data) {
+			stale = append(stale, path)
+		}
+	}
+
+	originals := make(map[string][]byte)
+	data, err := os.ReadFile(own)
+	if err != nil {
+		return originals, stale, nil // nothing to update
+	}
+	updated := field.ReplaceAll(data, []byte("${1}"+name+"${2}"))
+	if bytes.Equal(updated, data) {
+		return originals, stale, nil
+	}
+	if err := os.WriteFile(own, updated, 0644); err != nil {
+		return nil, nil, fmt.Errorf("failed to update %s: %w", own, err)
+	}
+	originals[own] = data
+	return originals, stale, nil
+}
+
+var infoCmd = &bonzai.Cmd{
+	Name:    "info",
+	Short:   "show details of a namespace",
+	Usage:   "info [name]",
+	MaxArgs: 1,
+	Long: `
+Show a namespace's dotfiles path, local-bin directory, backup dirs, whether
+its arara.yaml loads, the git status of the repository and whether the
+links it declares are in place. Without a name the active namespace is
+shown.
+
+Example:
+  arara namespace info
+  arara namespace info work
+`,
+	Do: func(x *bonzai.Cmd, args ...string) error {
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			return err
+		}
+
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		} else {
+			cwd, _ := os.Getwd()
+			name = gc.ActiveResolution(cwd).Name
+		}
+		if name == "" {
+			return fmt.Errorf("no active namespace. Use 'arara namespace add <name> <path>' first")
+		}
+		info, ok := gc.Config.Configs[name]
+		if !ok {
+			return fmt.Errorf("namespace not found: %s", name)
+		}
+
+		fmt.Println(name)
+		if _, err := os.Stat(info.Path); err != nil {
+			fmt.Printf("  path:       %s (missing)\n", info.Path)
+		} else {
+			fmt.Printf("  path:       %s\n", info.Path)
+		}
+		if info.LocalBin == "" {
+			fmt.Println("  local-bin:  none")
+		} else if dir := localBinDir(info.LocalBin); dirExists(dir) {
+			fmt.Printf("  local-bin:  %s\n", dir)
+		} else {
+			fmt.Printf("  local-bin:  %s (not created yet)\n", dir)
+		}
+
+		cfg, cfgErr := config.LoadMergedConfig(filepath.Join(info.Path, "arara.yaml"))
+		backupDirs := info.Dirs
+		if cfgErr == nil {
+			backupDirs = append(append([]string{}, info.Dirs...), cfg.Setup.BackupDirs...)
+		}
+		if len(backupDirs) == 0 {
+			fmt.Println("  backup:     none")
+		} else {
+			fmt.Printf("  backup:     %s\n", strings.Join(backupDirs, ", "))
+		}
+
+		if cfgErr != nil {
+			fmt.Printf("  config:     invalid, %v\n", cfgErr)
+		} else {
+			summary := fmt.Sprintf("%d scripts, %d build steps", len(cfg.Scripts.Install), len(cfg.Build.Steps))
+			if cfg.Extends != "" {
+				summary += ", extends " + cfg.Extends
+			}
+			fmt.Printf("  config:     ok (%s)\n", summary)
+		}
+		fmt.Printf("  git:        %s\n", gitStatus(info.Path))
+
+		if cfgErr != nil {
+			return nil
+		}
+		statuses := linkHealth(cfg, info.Path)
+		broken := 0
+		for _, s := range statuses {
+			if s.Problem != "" {
+				broken++
+			}
+		}
+		switch {
+		case len(statuses) == 0:
+			fmt.Println("  links:      none declared")
+		case broken == 0:
+			fmt.Printf("  links:      all %d in place\n", len(statuses))
+		default:
+			fmt.Printf("  links:      %d of %d broken\n", broken, len(statuses))
+			for _, s := range statuses {
+				if s.Problem != "" {
+					fmt.Printf("    ✗ %s: %s\n", s.Link.Target, s.Problem)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+var doctorCmd = &bonzai.Cmd{
+	Name:  "doctor",
+	Short: "check registered namespaces for problems",
+	Long: `
+Check every registered namespace and report those whose path no longer
+exists, that have no arara.yaml, or whose arara.yaml fails to load,
+including the namespaces it extends. Fails if any problem is found, so
+it can be used in scripts. An arara.yaml declaring another registered
+namespace is only a warning.
+`,
+	Do: func(x *bonzai.Cmd, args ...string) error {
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			return err
+		}
+		if len(gc.Config.Namespaces) == 0 {
+			fmt.Println("No namespaces registered")
+			return nil
+		}
+
+		unhealthy := 0
+		for _, ns := range gc.Config.Namespaces {
+			found, warnings := problems(gc, ns)
+			if len(found) == 0 {
+				fmt.Printf("✓ %s\n", ns)
+			} else {
+				unhealthy++
+				fmt.Printf("✗ %s\n", ns)
+			}
+			for _, p := range found {
+				fmt.Printf("    %s\n", p)
+			}
+			for _, w := range warnings {
+				fmt.Printf("    warning: %s\n", w)
+			}
+		}
+
+		// Settings left behind for names that aren't registered
+		for ns := range gc.Config.Configs {
+			if !contains(gc.Config.Namespaces, ns) {
+				fmt.Printf("? %s has settings but is not listed in namespaces\n", ns)
+			}
+		}
+
+		if unhealthy > 0 {
+			return fmt.Errorf("%d of %d namespaces have problems", unhealthy, len(gc.Config.Namespaces))
+		}
+		fmt.Printf("All %d namespaces are healthy\n", len(gc.Config.Namespaces))
+		return nil
+	},
+}
+
+var exportCmd = &bonzai.Cmd{
+	Name:    "export",
+	Short:   "export namespaces as a portable bundle",
+	Usage:   "export [file]",
+	MaxArgs: 1,
+	Long: `
+Write the registered namespaces as a portable bundle, to file or to
+stdout. Each namespace is recorded with its git remote, local-bin, backup
+dirs and path, written relative to the home directory (~/) when it is
+under it, so the bundle works on another machine:
+
+  namespaces:
+    - name: work
+      path: ~/dotfiles/work
+      remote: git@github.com:me/work-dotfiles.git
+      local-bin: work
+
+Recreate the registry elsewhere with 'arara namespace import'.
+
+Example:
+  arara namespace export namespaces.yaml
+`,
+	Do: func(x *bonzai.Cmd, args ...string) error {
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			return err
+		}
+
+		b := newBundle(gc)
+		for _, e := range b.Namespaces {
+			if e.Remote == "" {
+				fmt.Fprintf(os.Stderr, "warning: %s has no git remote, importing it needs %s to exist\n", e.Name, e.Path)
+			}
+		}
+		data, err := yaml.Marshal(b)
+		if err != nil {
+			return fmt.Errorf("failed to marshal bundle: %w", err)
+		}
+		data = append([]byte("# arara namespaces, restore with 'arara namespace import'\n"), data...)
+
+		if len(args) == 0 {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(args[0], data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", args[0], err)
+		}
+		fmt.Printf("Exported %d namespaces to %s\n", len(b.Namespaces), args[0])
+		return nil
+	},
+}
+
+var importCmd = &bonzai.Cmd{
+	Name:    "import",
+	Short:   "import namespaces from a bundle",
+	Usage:   "import [-n|--dry-run] <file>",
+	MinArgs: 1,
+	Long: `
+Register the namespaces of a bundle written by 'arara namespace export'.
+Repositories missing from their recorded path are cloned from their
+remote first, ~/ paths being relative to this machine's home directory.
+Namespaces that are already registered are left untouched.
+
+Options:
+  -n, --dry-run: Show what would be cloned and registered
+
+Example:
+  arara namespace import namespaces.yaml
+`,
+	Do: func(x *bonzai.Cmd, args ...string) error {
+		var dryRun bool
+		var rest []string
+		for _, arg := range args {
+			switch arg {
+			case "-n", "--dry-run":
+				dryRun = true
+			default:
+				rest = append(rest, arg)
+			}
+		}
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: arara namespace import [-n|--dry-run] <file>")
+		}
+
+		data, err := os.ReadFile(rest[0])
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		var b bundle
+		if err := yaml.Unmarshal(data, &b); err != nil {
+			return fmt.Errorf("failed to parse bundle %s: %w", rest[0], err)
+		}
+
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			return err
+		}
+
+		var errs []string
+		for _, e := range b.Namespaces {
+			if e.Name == "" {
+				errs = append(errs, fmt.Sprintf("entry for %s has no name", e.Path))
+				continue
+			}
+			if contains(gc.Config.Namespaces, e.Name) {
+				fmt.Printf("Skipping %s: already registered\n", e.Name)
+				continue
+			}
+			path, err := localPath(e.Path)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", e.Name, err))
+				continue
+			}
+
+			if !dirExists(path) {
+				if e.Remote == "" {
+					errs = append(errs, fmt.Sprintf("%s: %s does not exist and no remote is recorded", e.Name, path))
+					continue
+				}
+				if dryRun {
+					fmt.Printf("Would clone %s into %s\n", e.Remote, path)
+				} else if _, err := cloneRepo(gc, e.Name, e.Remote, path); err != nil {
+					errs = append(errs, fmt.Sprintf("%s: %v", e.Name, err))
+					continue
+				}
+			}
+			if dryRun {
+				fmt.Printf("Would add namespace '%s' pointing to %s\n", e.Name, path)
+				continue
+			}
+
+			gc.Config.Namespaces = append(gc.Config.Namespaces, e.Name)
+			gc.Config.Configs[e.Name] = config.NSInfo{
+				Path:     path,
+				LocalBin: e.LocalBin,
+				Dirs:     e.BackupDirs,
+			}
+			if err := gc.Save(); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+			fmt.Printf("Added namespace '%s' pointing to %s\n", e.Name, path)
+		}
+
+		if len(errs) > 0 {
+			return fmt.Errorf("failed to import: %s", strings.Join(errs, "; "))
+		}
+		return nil
+	},
+}
@@ -1,12 +1,17 @@
 package namespace_test
 
 import (
+	"bytes"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/BuddhiLW/arara/internal/app/namespace"
 	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/state"
 	bonzaiVars "github.com/rwxrob/bonzai/vars"
 )
 
@@ -166,3 +171,451 @@ func TestRemoveNamespace(t *testing.T) {
 		})
 	}
 }
+
+func TestCurrentNamespace(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv("ARARA_ACTIVE_NAMESPACE", "")
+
+	dotfiles := filepath.Join(tmpDir, "dotfiles")
+	if err := namespace.Cmd.Cmds[4].Do(nil, "test", dotfiles); err != nil {
+		t.Fatal(err)
+	}
+
+	// Inside the repository its namespace applies without switching
+	origDir, _ := os.Getwd()
+	if err := os.Chdir(dotfiles); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(origDir)
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := namespace.Cmd.Cmds[6].Do(nil) // currentCmd
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("currentCmd.Do() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	output := buf.String()
+	for _, want := range []string{"test\n", "path:   " + dotfiles, "source: directory", filepath.Join(dotfiles, "arara.yaml")} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+// gitRepo creates a git repository in dir committing files
+func gitRepo(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func TestCloneNamespace(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	testConfig, err := os.ReadFile(filepath.Join("testdata", "arara.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := filepath.Join(tmpDir, "remote")
+	gitRepo(t, remote, map[string]string{"arara.yaml": string(testConfig)})
+	plain := filepath.Join(tmpDir, "plain")
+	gitRepo(t, plain, map[string]string{"README": "not dotfiles"})
+
+	cloneCmd := namespace.Cmd.Cmds[7]
+
+	t.Run("Valid", func(t *testing.T) {
+		dest := filepath.Join(tmpDir, "clones", "work")
+		if err := cloneCmd.Do(nil, "work", "file://"+remote, dest); err != nil {
+			t.Fatalf("cloneCmd.Do() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "arara.yaml")); err != nil {
+			t.Errorf("arara.yaml not cloned: %v", err)
+		}
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info, ok := gc.Config.Configs["work"]; !ok || info.Path != dest {
+			t.Errorf("namespace work = %+v, want path %s", info, dest)
+		}
+	})
+
+	t.Run("DuplicateNamespace", func(t *testing.T) {
+		if err := cloneCmd.Do(nil, "work", remote, filepath.Join(tmpDir, "clones", "again")); err == nil {
+			t.Error("expected an error for an existing namespace")
+		}
+	})
+
+	t.Run("NoConfig", func(t *testing.T) {
+		dest := filepath.Join(tmpDir, "clones", "plain")
+		err := cloneCmd.Do(nil, "plain", plain, dest)
+		if err == nil || !strings.Contains(err.Error(), "not a dotfiles repository") {
+			t.Fatalf("expected not a dotfiles repository error, got %v", err)
+		}
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			t.Errorf("expected failed clone to be removed, stat error = %v", err)
+		}
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := gc.Config.Configs["plain"]; ok {
+			t.Error("namespace plain should not be registered")
+		}
+	})
+
+	t.Run("DeclaredNamespace", func(t *testing.T) {
+		personal := filepath.Join(tmpDir, "personal")
+		gitRepo(t, personal, map[string]string{"arara.yaml": "namespace: personal\nname: personal\n"})
+
+		dest := filepath.Join(tmpDir, "clones", "other")
+		err := cloneCmd.Do(nil, "other", personal, dest)
+		if err == nil || !strings.Contains(err.Error(), "declares namespace 'personal'") {
+			t.Fatalf("expected a declared namespace error, got %v", err)
+		}
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			t.Errorf("expected rejected clone to be removed, stat error = %v", err)
+		}
+
+		// Under its declared name it loads fine
+		if err := cloneCmd.Do(nil, "personal", personal, filepath.Join(tmpDir, "clones", "personal")); err != nil {
+			t.Errorf("cloneCmd.Do() error = %v", err)
+		}
+	})
+
+	t.Run("Setup", func(t *testing.T) {
+		home := filepath.Join(tmpDir, "home")
+		if err := os.MkdirAll(home, 0755); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("HOME", home)
+		linked := filepath.Join(tmpDir, "linked")
+		gitRepo(t, linked, map[string]string{
+			"arara.yaml": "setup:\n  config_links:\n    - source: $DOTFILES/bashrc\n      target: $HOME/.bashrc\n",
+			"bashrc":     "# bashrc\n",
+		})
+
+		// Backup and link run in this process, on the clone
+		dest := filepath.Join(tmpDir, "clones", "linked")
+		if _, err := captureStdout(t, func() error { return cloneCmd.Do(nil, "--setup", "linked", linked, dest) }); err != nil {
+			t.Fatalf("cloneCmd.Do() error = %v", err)
+		}
+		if target, err := os.Readlink(filepath.Join(home, ".bashrc")); err != nil || target != filepath.Join(dest, "bashrc") {
+			t.Errorf(".bashrc links to %q (%v), want %s", target, err, filepath.Join(dest, "bashrc"))
+		}
+		backups, _ := filepath.Glob(filepath.Join(home, "dotbk-*"))
+		if len(backups) != 1 {
+			t.Errorf("expected a backup directory in %s, got %v", home, backups)
+		}
+	})
+}
+
+// captureStdout returns what fn prints to stdout
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := fn()
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	return buf.String(), err
+}
+
+func TestRenameNamespace(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv("XDG_STATE_HOME", filepath.Join(tmpDir, "state"))
+
+	// work declares itself in its arara.yaml and child extends it
+	dotfiles := filepath.Join(tmpDir, "dotfiles")
+	child := filepath.Join(tmpDir, "child")
+	files := map[string]string{
+		filepath.Join(dotfiles, "arara.yaml"): "namespace: work\nname: work\n",
+		filepath.Join(child, "arara.yaml"):    "name: child\nextends: \"work\" # team base\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ns := range [][2]string{{"first", filepath.Join(tmpDir, "first")}, {"work", dotfiles}, {"child", child}} {
+		if err := os.MkdirAll(ns[1], 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := namespace.Cmd.Cmds[4].Do(nil, ns[0], ns[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bonzaiVars.Data.Set("active-namespace", "work"); err != nil {
+		t.Fatal(err)
+	}
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.MarkInstalled("work", "docker", "")
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	renameCmd := namespace.Cmd.Cmds[8]
+	out, err := captureStdout(t, func() error { return renameCmd.Do(nil, "work", "acme") })
+	if err != nil {
+		t.Fatalf("renameCmd.Do() error = %v", err)
+	}
+
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(gc.Config.Namespaces, " "); got != "first acme child" {
+		t.Errorf("namespaces = %q, want %q", got, "first acme child")
+	}
+	if _, ok := gc.Config.Configs["work"]; ok {
+		t.Error("old namespace config still exists")
+	}
+	if info := gc.Config.Configs["acme"]; info.Path != dotfiles {
+		t.Errorf("acme path = %q, want %q", info.Path, dotfiles)
+	}
+	if active, _ := bonzaiVars.Data.Get("active-namespace"); active != "acme" {
+		t.Errorf("active namespace = %q, want acme", active)
+	}
+	st, err = state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.IsInstalled("acme", "docker") || st.IsInstalled("work", "docker") {
+		t.Error("installed scripts should move to the new name")
+	}
+
+	// The renamed repository follows the rename and loads again
+	if data, _ := os.ReadFile(filepath.Join(dotfiles, "arara.yaml")); string(data) != "namespace: acme\nname: work\n" {
+		t.Errorf("arara.yaml of acme = %q", data)
+	}
+	t.Setenv("TEST_MODE", "")
+	if _, err := config.LoadConfig(filepath.Join(dotfiles, "arara.yaml")); err != nil {
+		t.Errorf("acme fails to load after the rename: %v", err)
+	}
+
+	// The child may be shared, so it is only pointed out
+	if data, _ := os.ReadFile(filepath.Join(child, "arara.yaml")); string(data) != files[filepath.Join(child, "arara.yaml")] {
+		t.Errorf("arara.yaml of child changed to %q", data)
+	}
+	if want := filepath.Join(child, "arara.yaml") + " still refers to 'work'"; !strings.Contains(out, want) {
+		t.Errorf("output does not mention %q:\n%s", want, out)
+	}
+
+	for _, args := range [][]string{{"missing", "other"}, {"acme", "first"}} {
+		if err := renameCmd.Do(nil, args...); err == nil {
+			t.Errorf("renameCmd.Do(%q) expected an error", args)
+		}
+	}
+}
+
+func TestNamespaceInfoAndDoctor(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv("ARARA_ACTIVE_NAMESPACE", "")
+	t.Setenv("HOME", filepath.Join(tmpDir, "home"))
+
+	// A repository linking two files, one of them in place
+	dotfiles := filepath.Join(tmpDir, "dotfiles")
+	if err := os.WriteFile(filepath.Join(dotfiles, "arara.yaml"), []byte(`
+name: test
+setup:
+  backup_dirs: ["$HOME/.config"]
+  config_links:
+    - source: "$DOTFILES/.bashrc"
+      target: "$HOME/.bashrc"
+    - source: "$DOTFILES/.vimrc"
+      target: "$HOME/.vimrc"
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "home"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dotfiles, ".bashrc"), filepath.Join(tmpDir, "home", ".bashrc")); err != nil {
+		t.Fatal(err)
+	}
+	if err := namespace.Cmd.Cmds[4].Do(nil, "test", dotfiles); err != nil {
+		t.Fatal(err)
+	}
+
+	infoCmd, doctorCmd := namespace.Cmd.Cmds[9], namespace.Cmd.Cmds[10]
+
+	output, err := captureStdout(t, func() error { return infoCmd.Do(nil, "test") })
+	if err != nil {
+		t.Fatalf("infoCmd.Do() error = %v", err)
+	}
+	for _, want := range []string{
+		"path:       " + dotfiles,
+		"local-bin:  none",
+		"backup:     $HOME/.config",
+		"config:     ok",
+		"links:      1 of 2 broken",
+		filepath.Join(tmpDir, "home", ".vimrc") + ": missing",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("info output missing %q:\n%s", want, output)
+		}
+	}
+
+	output, err = captureStdout(t, func() error { return doctorCmd.Do(nil) })
+	if err != nil || !strings.Contains(output, "✓ test") {
+		t.Errorf("doctorCmd.Do() error = %v, output:\n%s", err, output)
+	}
+
+	// A namespace whose repository is gone and one whose arara.yaml is broken
+	broken := filepath.Join(tmpDir, "broken")
+	if err := os.MkdirAll(broken, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(broken, "arara.yaml"), []byte("scripts: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gone := filepath.Join(tmpDir, "gone")
+	if err := os.MkdirAll(gone, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for ns, path := range map[string]string{"broken": broken, "gone": gone} {
+		if err := namespace.Cmd.Cmds[4].Do(nil, ns, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.RemoveAll(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second namespace over a repository declaring test only warns
+	if err := namespace.Cmd.Cmds[4].Do(nil, "alias", dotfiles); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dotfiles, "arara.yaml"), []byte("namespace: test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err = captureStdout(t, func() error { return doctorCmd.Do(nil) })
+	if err == nil || err.Error() != "2 of 4 namespaces have problems" {
+		t.Errorf("doctorCmd.Do() error = %v, want 2 of 4 namespaces have problems", err)
+	}
+	for _, want := range []string{"✓ test", "✗ broken", "arara.yaml fails to load", "✗ gone", "path " + gone + " does not exist",
+		"✓ alias\n    warning: arara.yaml declares namespace test"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("doctor output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestExportImportNamespaces(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv("HOME", filepath.Join(tmpDir, "home"))
+
+	testConfig, err := os.ReadFile(filepath.Join("testdata", "arara.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := filepath.Join(tmpDir, "remote")
+	gitRepo(t, remote, map[string]string{"arara.yaml": string(testConfig)})
+
+	// One namespace cloned under the home directory, one elsewhere
+	// without a remote
+	work := filepath.Join(tmpDir, "home", "dotfiles", "work")
+	if out, err := exec.Command("git", "clone", "-q", remote, work).CombinedOutput(); err != nil {
+		t.Fatalf("git clone: %v\n%s", err, out)
+	}
+	dotfiles := filepath.Join(tmpDir, "dotfiles")
+	for ns, path := range map[string]string{"work": work, "plain": dotfiles} {
+		if err := namespace.Cmd.Cmds[4].Do(nil, ns, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exportCmd, importCmd := namespace.Cmd.Cmds[11], namespace.Cmd.Cmds[12]
+	bundlePath := filepath.Join(tmpDir, "namespaces.yaml")
+	if _, err := captureStdout(t, func() error { return exportCmd.Do(nil, bundlePath) }); err != nil {
+		t.Fatalf("exportCmd.Do() error = %v", err)
+	}
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"name: work", "path: ~/dotfiles/work", "remote: " + remote, "path: " + dotfiles} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("bundle missing %q:\n%s", want, data)
+		}
+	}
+
+	// A new machine with another home and an empty registry
+	newHome := filepath.Join(tmpDir, "newhome")
+	t.Setenv("HOME", newHome)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "newconfig"))
+
+	output, err := captureStdout(t, func() error { return importCmd.Do(nil, "--dry-run", bundlePath) })
+	if err != nil || !strings.Contains(output, "Would clone "+remote) {
+		t.Errorf("dry run error = %v, output:\n%s", err, output)
+	}
+	if _, err := os.Stat(filepath.Join(newHome, "dotfiles", "work")); !os.IsNotExist(err) {
+		t.Error("dry run must not clone")
+	}
+
+	if _, err := captureStdout(t, func() error { return importCmd.Do(nil, bundlePath) }); err != nil {
+		t.Fatalf("importCmd.Do() error = %v", err)
+	}
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newWork := filepath.Join(newHome, "dotfiles", "work")
+	if info := gc.Config.Configs["work"]; info.Path != newWork {
+		t.Errorf("work path = %q, want %q", info.Path, newWork)
+	}
+	if info := gc.Config.Configs["plain"]; info.Path != dotfiles {
+		t.Errorf("plain path = %q, want %q", info.Path, dotfiles)
+	}
+	if _, err := os.Stat(filepath.Join(newWork, "arara.yaml")); err != nil {
+		t.Errorf("work was not cloned: %v", err)
+	}
+
+	// Importing again leaves registered namespaces alone
+	output, err = captureStdout(t, func() error { return importCmd.Do(nil, bundlePath) })
+	if err != nil || !strings.Contains(output, "Skipping work: already registered") {
+		t.Errorf("second import error = %v, output:\n%s", err, output)
+	}
+}
@@ -0,0 +1,110 @@
+package namespace
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+// linkStatus is the state of a declared link on this machine
+type linkStatus struct {
+	Link    config.Link // expanded
+	Problem string      // empty when the link is in place
+}
+
+// linkHealth checks that the links of cfg, whose dotfiles are at
+// dotfiles, point where they are declared to
+func linkHealth(cfg *config.DotfilesConfig, dotfiles string) []linkStatus {
+	links := append(append([]config.Link{}, cfg.Setup.CoreLinks...), cfg.Setup.ConfigLinks...)
+	statuses := make([]linkStatus, len(links))
+	for i, l := range links {
+		l = l.Expand(dotfiles)
+		statuses[i] = linkStatus{Link: l}
+
+		info, err := os.Lstat(l.Target)
+		switch {
+		case err != nil:
+			statuses[i].Problem = "missing"
+		case info.Mode()&os.ModeSymlink == 0:
+			statuses[i].Problem = "not a symlink"
+		default:
+			if dest, _ := os.Readlink(l.Target); dest != l.Source {
+				statuses[i].Problem = "points to " + dest
+			}
+		}
+	}
+	return statuses
+}
+
+// gitStatus summarizes the git working tree at dir: its branch and
+// whether it has uncommitted changes
+func gitStatus(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "status", "--porcelain", "--branch").Output()
+	if err != nil {
+		return "not a git repository"
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	branch := strings.TrimPrefix(lines[0], "## ")
+	if changes := len(lines) - 1; changes > 0 {
+		return fmt.Sprintf("%s, %d uncommitted changes", branch, changes)
+	}
+	return branch + ", clean"
+}
+
+// localBinDir is where 'arara create bin' puts the executables of a
+// namespace with local-bin set
+func localBinDir(localBin string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "bin", localBin)
+}
+
+// problems returns what is wrong with namespace ns: a path that no longer
+// exists or an arara.yaml that fails to load. Warnings are unusual but
+// working setups, such as an arara.yaml declaring another registered
+// namespace.
+func problems(gc *config.GlobalConfig, ns string) (found, warnings []string) {
+	info, ok := gc.Configs[ns]
+	if !ok {
+		return []string{"no configuration, see 'arara namespace edit'"}, nil
+	}
+	if info.Path == "" {
+		return []string{"no path configured"}, nil
+	}
+	if _, err := os.Stat(info.Path); err != nil {
+		return []string{fmt.Sprintf("path %s does not exist", info.Path)}, nil
+	}
+
+	configPath := filepath.Join(info.Path, "arara.yaml")
+	if _, err := os.Stat(configPath); err != nil {
+		return []string{fmt.Sprintf("no arara.yaml in %s", info.Path)}, nil
+	}
+	cfg, err := config.LoadMergedConfig(configPath)
+	if err != nil {
+		return []string{fmt.Sprintf("arara.yaml fails to load: %v", err)}, nil
+	}
+
+	if cfg.Namespace != "" && cfg.Namespace != ns {
+		warnings = append(warnings, fmt.Sprintf("arara.yaml declares namespace %s", cfg.Namespace))
+	}
+	return nil, warnings
+}
+
+// dirExists reports whether dir is an existing directory
+func dirExists(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// contains reports whether list has s
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
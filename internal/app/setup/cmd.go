@@ -4,6 +4,7 @@ import (
 	"github.com/rwxrob/bonzai"
 	"github.com/rwxrob/bonzai/cmds/help"
 	"github.com/BuddhiLW/arara/internal/app/backup"
+	"github.com/BuddhiLW/arara/internal/app/blocks"
 	"github.com/BuddhiLW/arara/internal/app/link"
 )
 
@@ -22,6 +23,7 @@ var Cmd = &bonzai.Cmd{
 	Cmds: []*bonzai.Cmd{
 		backup.Cmd,   // Backup existing dotfiles
 		link.Cmd,     // Create symlinks
+		blocks.Cmd,   // Write managed blocks into files
 		restoreCmd,   // Restore from backup
 		help.Cmd,     // Show help
 	},
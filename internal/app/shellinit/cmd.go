@@ -0,0 +1,211 @@
+package shellinit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rwxrob/bonzai"
+	"github.com/rwxrob/bonzai/cmds/help"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+var Cmd = &bonzai.Cmd{
+	Name:    "shell-init",
+	Short:   "print shell integration to eval from rc files",
+	Usage:   "shell-init [--prompt] <bash|zsh|fish>",
+	MinArgs: 1,
+	Long: `
+Print a snippet that sets up arara in the shell. Evaluate it from the
+shell's rc file:
+
+  # ~/.bashrc
+  eval "$(arara shell-init bash)"
+
+  # ~/.zshrc
+  eval "$(arara shell-init zsh)"
+
+  # ~/.config/fish/config.fish
+  arara shell-init fish | source
+
+The snippet:
+
+- adds the local-bin directory of every namespace to PATH, skipping
+  those already in it
+- exports the env map of the active namespace's arara.yaml, including
+  the namespace it extends
+- enables tab completion of arara commands
+
+With --prompt it also prefixes the prompt with the active namespace in
+parentheses. The namespace follows the working directory, see 'arara
+namespace current'. The __arara_prompt function printing it is defined
+either way, to place it in a custom prompt.
+`,
+	Cmds: []*bonzai.Cmd{help.Cmd},
+	Do: func(caller *bonzai.Cmd, args ...string) error {
+		var prompt bool
+		var rest []string
+		for _, arg := range args {
+			if arg == "--prompt" {
+				prompt = true
+				continue
+			}
+			rest = append(rest, arg)
+		}
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: arara shell-init [--prompt] <bash|zsh|fish>")
+		}
+		sh, ok := shells[rest[0]]
+		if !ok {
+			return fmt.Errorf("unsupported shell: %s (supported: bash, zsh, fish)", rest[0])
+		}
+
+		gc, err := config.NewGlobalConfig()
+		if err != nil {
+			return err
+		}
+
+		home, _ := os.UserHomeDir()
+		var bins []string
+		for _, ns := range gc.Namespaces {
+			if info, ok := gc.Configs[ns]; ok && info.LocalBin != "" {
+				bins = append(bins, filepath.Join(home, ".local", "bin", info.LocalBin))
+			}
+		}
+
+		// A broken arara.yaml must not break the shell starting up
+		var env map[string]string
+		cwd, _ := os.Getwd()
+		if res := gc.ActiveResolution(cwd); res.Path != "" {
+			cfg, err := config.LoadMergedConfig(filepath.Join(res.Path, "arara.yaml"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "arara: not exporting env of %s: %v\n", res.Name, err)
+			} else {
+				env = cfg.Env
+			}
+		}
+
+		fmt.Print(snippet(sh, bins, env, prompt))
+		return nil
+	},
+}
+
+// shell writes the statements of the snippet in a shell's syntax
+type shell struct {
+	addPath    func(dir string) string
+	export     func(key, value string) string
+	completion string
+	prompt     string // defines __arara_prompt
+	usePrompt  string // prefixes the prompt with __arara_prompt
+}
+
+// posixPath, posixExport and posixPrompt are shared by bash and zsh
+func posixPath(dir string) string {
+	return fmt.Sprintf("case \":$PATH:\" in *:%s:*) ;; *) export PATH=%s\"${PATH:+:$PATH}\" ;; esac", shQuote(dir), shQuote(dir))
+}
+
+func posixExport(k, v string) string {
+	return fmt.Sprintf("export %s=%s", k, shQuote(v))
+}
+
+const posixPrompt = `__arara_prompt() {
+  local ns
+  ns=$(command arara namespace current --short 2>/dev/null)
+  [ -n "$ns" ] && printf '(%s) ' "$ns"
+}`
+
+var shells = map[string]shell{
+	"bash": {
+		addPath:    posixPath,
+		export:     posixExport,
+		completion: "complete -C arara arara",
+		prompt:     posixPrompt,
+		usePrompt:  `case "$PS1" in *__arara_prompt*) ;; *) PS1='$(__arara_prompt)'"$PS1" ;; esac`,
+	},
+	"zsh": {
+		addPath: posixPath,
+		export:  posixExport,
+		completion: `autoload -U +X bashcompinit && bashcompinit
+complete -C arara arara`,
+		prompt: posixPrompt,
+		usePrompt: `setopt PROMPT_SUBST
+case "$PROMPT" in *__arara_prompt*) ;; *) PROMPT='$(__arara_prompt)'"$PROMPT" ;; esac`,
+	},
+	"fish": {
+		addPath: func(dir string) string {
+			return fmt.Sprintf("contains -- %s $PATH; or set -gx PATH %s $PATH", fishQuote(dir), fishQuote(dir))
+		},
+		export:     func(k, v string) string { return fmt.Sprintf("set -gx %s %s", k, fishQuote(v)) },
+		completion: `complete -c arara -f -a '(env COMP_LINE=(commandline -cp) arara)'`,
+		prompt: `function __arara_prompt
+    set -l ns (command arara namespace current --short 2>/dev/null)
+    test -n "$ns"; and printf '(%s) ' $ns
+end`,
+		usePrompt: `if not functions -q __arara_fish_prompt
+    functions -c fish_prompt __arara_fish_prompt
+    function fish_prompt
+        __arara_prompt
+        __arara_fish_prompt
+    end
+end`,
+	},
+}
+
+// envKey matches the variable names every shell accepts
+var envKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// snippet builds the init snippet adding bins to PATH and exporting env.
+// Env values may reference the process env and each other, they are
+// expanded in key order like the env of install scripts. Keys that are
+// not valid variable names are skipped with a warning, since the snippet
+// is evaluated and env may come from a shared base namespace.
+func snippet(sh shell, bins []string, env map[string]string, prompt bool) string {
+	var b strings.Builder
+	b.WriteString("# arara shell integration\n")
+	// Directories are prepended, so the first namespace comes first
+	for i := len(bins) - 1; i >= 0; i-- {
+		b.WriteString(sh.addPath(bins[i]) + "\n")
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	expanded := make(map[string]string)
+	lookup := func(k string) string {
+		if v, ok := expanded[k]; ok {
+			return v
+		}
+		return os.Getenv(k)
+	}
+	for _, k := range keys {
+		if !envKey.MatchString(k) {
+			fmt.Fprintf(os.Stderr, "arara: not exporting %q: not a valid variable name\n", k)
+			continue
+		}
+		expanded[k] = os.Expand(env[k], lookup)
+		b.WriteString(sh.export(k, expanded[k]) + "\n")
+	}
+
+	b.WriteString(sh.completion + "\n")
+	b.WriteString(sh.prompt + "\n")
+	if prompt {
+		b.WriteString(sh.usePrompt + "\n")
+	}
+	return b.String()
+}
+
+// shQuote quotes s for bash and zsh
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,116 @@
+package shellinit
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+func TestSnippet(t *testing.T) {
+	bins := []string{"/opt/arara bin/work", "/opt/it's/home"}
+	env := map[string]string{
+		"DOTFILES":                    "/opt/dotfiles",
+		"SCRIPTS":                     "$DOTFILES/scripts",
+		"X;touch /tmp/arara-injected": "1",
+		"A B":                         "2",
+	}
+
+	t.Run("bash", func(t *testing.T) {
+		if _, err := exec.LookPath("bash"); err != nil {
+			t.Skip("bash is not installed")
+		}
+		out := snippet(shells["bash"], bins, env, true)
+
+		// Evaluating twice must not add the directories twice
+		script := "PS1='$ '\n" + out + "\n" + out + `
+printf '%s\n' "$PATH" "$SCRIPTS" "$PS1"
+type __arara_prompt >/dev/null && complete -p arara
+`
+		cmd := exec.Command("bash", "--norc", "-c", script)
+		cmd.Env = append(os.Environ(), "PATH=/usr/bin:/bin")
+		got, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("bash failed: %v\n%s\nsnippet:\n%s", err, got, out)
+		}
+		lines := strings.Split(strings.TrimSpace(string(got)), "\n")
+		want := []string{
+			"/opt/arara bin/work:/opt/it's/home:/usr/bin:/bin",
+			"/opt/dotfiles/scripts",
+			"$(__arara_prompt)$ ",
+			"complete -C 'arara' arara",
+		}
+		if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+			t.Errorf("got:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+		}
+	})
+
+	t.Run("fish", func(t *testing.T) {
+		out := snippet(shells["fish"], bins, env, false)
+		for _, want := range []string{
+			`contains -- '/opt/it\'s/home' $PATH; or set -gx PATH '/opt/it\'s/home' $PATH`,
+			"set -gx SCRIPTS '/opt/dotfiles/scripts'",
+			"complete -c arara -f -a '(env COMP_LINE=(commandline -cp) arara)'",
+			"function __arara_prompt",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("fish snippet missing %q:\n%s", want, out)
+			}
+		}
+		if strings.Contains(out, "fish_prompt") {
+			t.Errorf("prompt should only be changed with --prompt:\n%s", out)
+		}
+		if strings.Contains(out, "injected") || strings.Contains(out, "A B") {
+			t.Errorf("invalid env keys should be skipped:\n%s", out)
+		}
+	})
+}
+
+func TestShellInitCmd(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+	t.Setenv("HOME", filepath.Join(tmpDir, "home"))
+	t.Setenv("ARARA_ACTIVE_NAMESPACE", "work")
+
+	dotfiles := filepath.Join(tmpDir, "work")
+	if err := os.MkdirAll(dotfiles, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dotfiles, "arara.yaml"), []byte("env:\n  EDITOR: nvim\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.AddNamespace("work", dotfiles, "work-bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err = Cmd.Do(Cmd, "zsh")
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("Cmd.Do() error = %v", err)
+	}
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	out := buf.String()
+	for _, want := range []string{filepath.Join(tmpDir, "home", ".local", "bin", "work-bin"), "export EDITOR='nvim'", "bashcompinit"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	if err := Cmd.Do(Cmd, "tcsh"); err == nil || !strings.Contains(err.Error(), "unsupported shell") {
+		t.Errorf("expected unsupported shell error, got %v", err)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"strconv"
 
 	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/runlog"
 )
 
 // Add to package-level vars for testing
@@ -139,9 +140,22 @@ func syncScripts(cfg *config.DotfilesConfig, scriptsDir string) ([]config.Script
 			Path:        path,
 		}
 
+		// Metadata declared in the script header is the source of truth
+		header, err := parseHeader(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read header of %s: %w", path, err)
+		}
+		header.apply(&newScript)
+
 		if existing, exists := existingScripts[name]; exists {
 			// Check if configs differ beyond just the path
 			existing.Path = path // Update path
+			if !header.empty() {
+				// Header wins, other existing fields are kept
+				header.apply(&existing)
+				newScripts = append(newScripts, existing)
+				continue
+			}
 			if existing.Description != newScript.Description {
 				conflicts = append(conflicts, scriptConflict{
 					name:     name,
@@ -215,6 +229,7 @@ func resolveConflictsInteractive(conflicts []scriptConflict) (map[string]config.
 var Cmd = &bonzai.Cmd{
 	Name:  "sync",
 	Short: "synchronize install scripts from active namespace",
+	Usage: "sync [--pin]",
 	Long: `
 Synchronize install scripts from the active namespace into the local arara.yaml.
 This will:
@@ -222,6 +237,24 @@ This will:
 2. Add them to the local arara.yaml's install scripts section
 3. Preserve existing script descriptions and configurations
 
+Scripts can declare their own metadata in the leading comment block,
+which takes precedence over arara.yaml:
+
+  #!/bin/bash
+  # arara: description=Install Docker Engine
+  # arara: os=debian
+  # arara: pkgmgr=apt
+  # arara: depends=curl, ca-certificates
+
+Recognized keys are description, os, arch, shell, pkgmgr, kernel and
+depends. Only the fields a header declares are overwritten.
+
+With --pin, the sha256 of every script is recorded in its sha256 field,
+and those of its uninstall and upgrade actions in action_sha256.
+'arara install' refuses to run a pinned script or action whose content
+no longer matches, guarding against accidental or malicious edits. Run
+'arara sync --pin' again after an intended change.
+
 Changes are applied atomically with automatic rollback on failure.
 `,
 	Cmds: []*bonzai.Cmd{
@@ -231,6 +264,13 @@ Changes are applied atomically with automatic rollback on failure.
 		configPath := "arara.yaml"
 		scriptsDir := "scripts/install"
 
+		pin := false
+		for _, arg := range args {
+			if arg == "--pin" {
+				pin = true
+			}
+		}
+
 		// Begin transaction
 		tx, err := beginTransaction(configPath)
 		if err != nil {
@@ -267,6 +307,21 @@ Changes are applied atomically with automatic rollback on failure.
 			}
 		}
 
+		// Pin the current hash of every script
+		if pin {
+			for i := range newScripts {
+				var hash string
+				hash, err = runlog.FileHash(newScripts[i].Path)
+				if err != nil {
+					return fmt.Errorf("failed to hash %s: %w", newScripts[i].Path, err)
+				}
+				newScripts[i].SHA256 = hash
+				if newScripts[i].ActionSHA256, err = actionHashes(newScripts[i]); err != nil {
+					return err
+				}
+			}
+		}
+
 		// Check for concurrent modifications before writing
 		if modified, err := tx.checkModified(); err != nil {
 			return err
@@ -300,6 +355,29 @@ Changes are applied atomically with automatic rollback on failure.
 	},
 }
 
+// actionHashes returns the sha256 of the uninstall and upgrade actions
+// of script that exist, keyed by action
+func actionHashes(script config.Script) (map[string]string, error) {
+	hashes := make(map[string]string)
+	for action, path := range map[string]string{"uninstall": script.Uninstall, "upgrade": script.Upgrade} {
+		if path == "" {
+			path = filepath.Join("scripts", action, script.Name)
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		hash, err := runlog.FileHash(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", path, err)
+		}
+		hashes[action] = hash
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	return hashes, nil
+}
+
 func copyFile(src, dst string) error {
 	source, err := os.Open(src)
 	if err != nil {
@@ -2,9 +2,12 @@ package sync_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -420,6 +423,57 @@ scripts:
 	}
 }
 
+func TestSyncCmd_Pin(t *testing.T) {
+	oldStdin, oldStdout := sync.Stdin, sync.Stdout
+	defer func() { sync.Stdin, sync.Stdout = oldStdin, oldStdout }()
+	sync.Stdin = strings.NewReader("1\n") // Keep existing description
+	sync.Stdout = io.Discard
+
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	if err := setupBasicConfig(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	// script1 has an uninstall action following the naming convention
+	if err := os.MkdirAll(filepath.Join("scripts", "uninstall"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("scripts", "uninstall", "script1"), []byte("#!/bin/sh\necho remove"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sync.Cmd.Do(sync.Cmd, "--pin"); err != nil {
+		t.Fatalf("sync.Cmd.Do(--pin) error = %v", err)
+	}
+
+	cfg, err := config.LoadConfig("arara.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Scripts.Install) != 2 {
+		t.Fatalf("got %d scripts, want 2", len(cfg.Scripts.Install))
+	}
+	for _, script := range cfg.Scripts.Install {
+		want := fmt.Sprintf("%x", sha256.Sum256([]byte("#!/bin/sh\necho "+script.Name)))
+		if script.SHA256 != want {
+			t.Errorf("%s sha256 = %q, want %q", script.Name, script.SHA256, want)
+		}
+
+		var actions map[string]string
+		if script.Name == "script1" {
+			actions = map[string]string{"uninstall": fmt.Sprintf("%x", sha256.Sum256([]byte("#!/bin/sh\necho remove")))}
+		}
+		if !reflect.DeepEqual(script.ActionSHA256, actions) {
+			t.Errorf("%s action_sha256 = %v, want %v", script.Name, script.ActionSHA256, actions)
+		}
+	}
+}
+
 func TestSyncCmd_NonInteractive(t *testing.T) {
 	// Test the non-interactive parts
 }
@@ -0,0 +1,113 @@
+package sync
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+// headerPrefix marks a metadata line in a script's leading comment block
+const headerPrefix = "arara:"
+
+// scriptHeader holds the metadata declared in a script's comment header
+type scriptHeader struct {
+	Description  string
+	Compat       *config.CompatConfig
+	Dependencies []string
+}
+
+// empty reports whether the script declared no metadata at all
+func (h scriptHeader) empty() bool {
+	return h.Description == "" && h.Compat == nil && len(h.Dependencies) == 0
+}
+
+// parseHeader reads the leading comment block of the script at path and
+// collects "# arara: key=value" lines. Recognized keys are description,
+// os, arch, shell, pkgmgr, kernel and depends (comma or space separated,
+// may be repeated). Unknown keys are ignored. Parsing stops at the first
+// line that is neither blank nor a comment.
+func parseHeader(path string) (scriptHeader, error) {
+	var h scriptHeader
+
+	f, err := os.Open(path)
+	if err != nil {
+		return h, err
+	}
+	defer f.Close()
+
+	compat := &config.CompatConfig{}
+	hasCompat := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+
+		rest, ok := strings.CutPrefix(strings.TrimSpace(strings.TrimLeft(line, "#")), headerPrefix)
+		if !ok {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(rest), "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+
+		switch key {
+		case "description", "desc":
+			h.Description = value
+		case "os":
+			compat.OS = value
+			hasCompat = true
+		case "arch":
+			compat.Arch = value
+			hasCompat = true
+		case "shell":
+			compat.Shell = value
+			hasCompat = true
+		case "pkgmgr":
+			compat.PkgMgr = value
+			hasCompat = true
+		case "kernel":
+			compat.Kernel = value
+			hasCompat = true
+		case "depends", "dependencies":
+			for _, dep := range strings.FieldsFunc(value, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			}) {
+				h.Dependencies = append(h.Dependencies, dep)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return h, err
+	}
+
+	if hasCompat {
+		h.Compat = compat
+	}
+
+	return h, nil
+}
+
+// apply overwrites the script fields declared in the header, leaving all
+// other fields untouched
+func (h scriptHeader) apply(script *config.Script) {
+	if h.Description != "" {
+		script.Description = h.Description
+	}
+	if h.Compat != nil {
+		script.Compat = h.Compat
+	}
+	if len(h.Dependencies) > 0 {
+		script.Dependencies = h.Dependencies
+	}
+}
@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    scriptHeader
+	}{
+		{
+			name: "FullHeader",
+			content: `#!/bin/bash
+# Installs docker
+# arara: description=Install Docker Engine
+# arara: os=debian
+#arara: pkgmgr="apt"
+# arara: depends=curl, ca-certificates
+# arara: depends=gnupg
+
+echo "# arara: os=ignored"
+`,
+			want: scriptHeader{
+				Description:  "Install Docker Engine",
+				Compat:       &config.CompatConfig{OS: "debian", PkgMgr: "apt"},
+				Dependencies: []string{"curl", "ca-certificates", "gnupg"},
+			},
+		},
+		{
+			name:    "NoHeader",
+			content: "#!/bin/sh\necho test\n",
+			want:    scriptHeader{},
+		},
+		{
+			name:    "UnknownKeysIgnored",
+			content: "#!/bin/sh\n# arara: color=blue\n# arara: malformed\n",
+			want:    scriptHeader{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "script")
+			if err := os.WriteFile(path, []byte(tt.content), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parseHeader(path)
+			if err != nil {
+				t.Fatalf("parseHeader() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHeader() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncScripts_Header(t *testing.T) {
+	dir := t.TempDir()
+	scriptsDir := filepath.Join(dir, "scripts", "install")
+	if err := os.MkdirAll(scriptsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	header := "#!/bin/sh\n# arara: description=From header\n# arara: os=linux\n"
+	if err := os.WriteFile(filepath.Join(scriptsDir, "docker"), []byte(header), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Existing entry has a different description and extra fields; the
+	// header wins without a conflict and extra fields are kept
+	cfg := &config.DotfilesConfig{}
+	cfg.Scripts.Install = []config.Script{
+		{
+			Name:        "docker",
+			Description: "From arara.yaml",
+			Path:        "old/path",
+			Args:        []string{"--stable"},
+		},
+	}
+
+	scripts, conflicts, err := syncScripts(cfg, scriptsDir)
+	if err != nil {
+		t.Fatalf("syncScripts() error = %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("expected no conflicts, got %v", conflicts)
+	}
+	if len(scripts) != 1 {
+		t.Fatalf("got %d scripts, want 1", len(scripts))
+	}
+
+	got := scripts[0]
+	if got.Description != "From header" {
+		t.Errorf("Description = %q, want %q", got.Description, "From header")
+	}
+	if got.Compat == nil || got.Compat.OS != "linux" {
+		t.Errorf("Compat = %+v, want os linux", got.Compat)
+	}
+	if len(got.Args) != 1 || got.Args[0] != "--stable" {
+		t.Errorf("Args = %v, existing args should be kept", got.Args)
+	}
+}
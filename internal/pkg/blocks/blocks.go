@@ -0,0 +1,199 @@
+// Package blocks manages named sections of text files delimited by marker
+// comments, so arara can own part of a file like .bashrc while the rest
+// stays the user's:
+//
+//	# >>> arara local-bin >>>
+//	export PATH="$HOME/.local/bin/work:$PATH"
+//	# <<< arara local-bin <<<
+//
+// Writing a block replaces its previous content in place, or appends it
+// to the file, and removing it leaves the rest of the file untouched.
+package blocks
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBin is the block 'arara create setup-path' keeps in ~/.bashrc,
+// adding the local-bin directory of every namespace to PATH
+const LocalBin = "local-bin"
+
+// Syntax is how comments are written in a file. End is empty for line
+// comments.
+type Syntax struct {
+	Start string
+	End   string
+}
+
+// ParseSyntax parses a comment syntax written as its start, optionally
+// followed by a space and its end, such as "#", "//" or "<!-- -->"
+func ParseSyntax(s string) Syntax {
+	start, end, _ := strings.Cut(strings.TrimSpace(s), " ")
+	return Syntax{Start: start, End: strings.TrimSpace(end)}
+}
+
+// syntaxes maps file extensions and names to their comment syntax
+var syntaxes = map[string]Syntax{
+	".vim":        {Start: `"`},
+	".vimrc":      {Start: `"`},
+	".lua":        {Start: "--"},
+	".sql":        {Start: "--"},
+	".hs":         {Start: "--"},
+	".el":         {Start: ";;"},
+	".lisp":       {Start: ";;"},
+	".ini":        {Start: ";"},
+	".js":         {Start: "//"},
+	".ts":         {Start: "//"},
+	".go":         {Start: "//"},
+	".jsonc":      {Start: "//"},
+	".css":        {Start: "/*", End: "*/"},
+	".html":       {Start: "<!--", End: "-->"},
+	".xml":        {Start: "<!--", End: "-->"},
+	".md":         {Start: "<!--", End: "-->"},
+	".Xresources": {Start: "!"},
+}
+
+// SyntaxFor guesses the comment syntax of the file at path from its
+// extension or name, defaulting to # as used by shells and most configs
+func SyntaxFor(path string) Syntax {
+	base := filepath.Base(path)
+	if s, ok := syntaxes[base]; ok {
+		return s
+	}
+	if s, ok := syntaxes[filepath.Ext(base)]; ok {
+		return s
+	}
+	return Syntax{Start: "#"}
+}
+
+// Markers returns the lines opening and closing block name
+func Markers(name string, syn Syntax) (begin, end string) {
+	comment := func(text string) string {
+		if syn.End == "" {
+			return syn.Start + " " + text
+		}
+		return syn.Start + " " + text + " " + syn.End
+	}
+	return comment(">>> arara " + name + " >>>"), comment("<<< arara " + name + " <<<")
+}
+
+// Set writes body as block name of content, replacing the block if it
+// exists and appending it otherwise. Reports whether content changed.
+func Set(content, name, body string, syn Syntax) (string, bool, error) {
+	begin, end := Markers(name, syn)
+	block := begin + "\n"
+	if body = strings.TrimRight(body, "\n"); body != "" {
+		block += body + "\n"
+	}
+	block += end + "\n"
+
+	start, stop, found, err := Find(content, begin, end)
+	if err != nil {
+		return content, false, err
+	}
+	if found {
+		updated := content[:start] + block + content[stop:]
+		return updated, updated != content, nil
+	}
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if content != "" {
+		content += "\n"
+	}
+	return content + block, true, nil
+}
+
+// Remove removes block name from content. Reports whether it was there.
+func Remove(content, name string, syn Syntax) (string, bool, error) {
+	begin, end := Markers(name, syn)
+	return RemoveBetween(content, begin, end)
+}
+
+// RemoveBetween removes the lines from begin to end, both included, and
+// the blank line Set puts before a block. Reports whether they were there.
+func RemoveBetween(content, begin, end string) (string, bool, error) {
+	start, stop, found, err := Find(content, begin, end)
+	if err != nil || !found {
+		return content, false, err
+	}
+	before := content[:start]
+	if strings.HasSuffix(before, "\n\n") {
+		before = before[:len(before)-1]
+	}
+	return before + content[stop:], true, nil
+}
+
+// Find locates the lines begin and end in content, returning the offset
+// of the start of begin and of the line following end
+func Find(content, begin, end string) (start, stop int, found bool, err error) {
+	start = -1
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case start == -1 && trimmed == begin:
+			start = offset
+		case start != -1 && trimmed == end:
+			return start, offset + len(line), true, nil
+		}
+		offset += len(line)
+	}
+	if start != -1 {
+		return 0, 0, false, fmt.Errorf("block started by %q is not closed by %q", begin, end)
+	}
+	return 0, 0, false, nil
+}
+
+// SetFile writes body as block name of the file at path, creating the
+// file if needed. Symlinks are followed and the mode is preserved.
+func SetFile(path, name, body string, syn Syntax) (bool, error) {
+	return update(path, true, func(content string) (string, bool, error) {
+		return Set(content, name, body, syn)
+	})
+}
+
+// RemoveFile removes block name from the file at path, if present
+func RemoveFile(path, name string, syn Syntax) (bool, error) {
+	return update(path, false, func(content string) (string, bool, error) {
+		return Remove(content, name, syn)
+	})
+}
+
+// update rewrites the file at path with fn, creating it if create is set
+func update(path string, create bool, fn func(string) (string, bool, error)) (bool, error) {
+	mode := os.FileMode(0644)
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && create:
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return false, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	default:
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	content, changed, err := fn(string(data))
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if !changed {
+		return false, nil
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,106 @@
+package blocks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	hash := Syntax{Start: "#"}
+	content := "alias ll='ls -l'"
+
+	got, changed, err := Set(content, "path", "export A=1\n", hash)
+	if err != nil || !changed {
+		t.Fatalf("Set() = %v, %v", changed, err)
+	}
+	want := "alias ll='ls -l'\n\n# >>> arara path >>>\nexport A=1\n# <<< arara path <<<\n"
+	if got != want {
+		t.Fatalf("appended:\n%q\nwant:\n%q", got, want)
+	}
+
+	if again, changed, _ := Set(got, "path", "export A=1", hash); changed || again != got {
+		t.Errorf("setting the same body changed the content:\n%q", again)
+	}
+
+	got, changed, _ = Set(got+"echo after\n", "path", "export A=2", hash)
+	want = "alias ll='ls -l'\n\n# >>> arara path >>>\nexport A=2\n# <<< arara path <<<\necho after\n"
+	if !changed || got != want {
+		t.Errorf("updated:\n%q\nwant:\n%q", got, want)
+	}
+
+	got, changed, _ = Remove(got, "path", hash)
+	if want := "alias ll='ls -l'\necho after\n"; !changed || got != want {
+		t.Errorf("removed:\n%q\nwant:\n%q", got, want)
+	}
+	if _, changed, _ := Remove(got, "path", hash); changed {
+		t.Error("removing a missing block reported a change")
+	}
+
+	if _, _, err := Set("# >>> arara path >>>\nexport A=1\n", "path", "", hash); err == nil {
+		t.Error("expected an error for an unclosed block")
+	}
+}
+
+func TestSyntax(t *testing.T) {
+	tests := map[string]Syntax{
+		"/home/u/.bashrc":            {Start: "#"},
+		"/home/u/.vimrc":             {Start: `"`},
+		"/home/u/.config/nvim/a.lua": {Start: "--"},
+		"/srv/index.html":            {Start: "<!--", End: "-->"},
+	}
+	for path, want := range tests {
+		if got := SyntaxFor(path); got != want {
+			t.Errorf("SyntaxFor(%s) = %+v, want %+v", path, got, want)
+		}
+	}
+	if got := ParseSyntax("/* */"); got != (Syntax{Start: "/*", End: "*/"}) {
+		t.Errorf("ParseSyntax = %+v", got)
+	}
+
+	begin, end := Markers("theme", Syntax{Start: "/*", End: "*/"})
+	if begin != "/* >>> arara theme >>> */" || end != "/* <<< arara theme <<< */" {
+		t.Errorf("Markers = %q, %q", begin, end)
+	}
+}
+
+func TestSetFile(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "real", "profile")
+	if err := os.MkdirAll(filepath.Dir(real), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(real, []byte("umask 022\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, ".profile")
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := SetFile(link, "env", "export X=1", Syntax{Start: "#"}); err != nil || !changed {
+		t.Fatalf("SetFile() = %v, %v", changed, err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink was replaced")
+	}
+	if info, _ := os.Stat(real); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	created := filepath.Join(dir, "new", "init.vim")
+	if _, err := SetFile(created, "plugins", "source x.vim", SyntaxFor(created)); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(created)
+	if want := "\" >>> arara plugins >>>\nsource x.vim\n\" <<< arara plugins <<<\n"; string(data) != want {
+		t.Errorf("created:\n%q\nwant:\n%q", data, want)
+	}
+
+	if changed, err := RemoveFile(filepath.Join(dir, "missing"), "env", Syntax{Start: "#"}); err != nil || changed {
+		t.Errorf("RemoveFile(missing) = %v, %v", changed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing")); err == nil {
+		t.Error("RemoveFile created the file")
+	}
+}
@@ -15,6 +15,9 @@ import (
 type Config struct {
 	Namespaces []string          `yaml:"namespaces"`
 	Configs    map[string]NSInfo `yaml:"configs"`
+	Privilege  string            `yaml:"privilege,omitempty"` // sudo, doas, run0, pkexec, none or auto
+
+	PackageCache string `yaml:"package_cache,omitempty"` // Install dependencies from this directory (see 'deps fetch')
 }
 
 // DotfilesConfig represents a local dotfiles configuration (arara.yaml)
@@ -23,8 +26,10 @@ type DotfilesConfig struct {
 	Description string            `yaml:"description"`
 	Env         map[string]string `yaml:"env,omitempty"`
 	Namespace   string            `yaml:"namespace"`
+	Extends     string            `yaml:"extends,omitempty"` // Base namespace this one layers over
 
-	Dependencies []string `yaml:"dependencies,omitempty"`
+	Dependencies     []Dependency               `yaml:"dependencies,omitempty"`
+	DependencyGroups map[string]DependencyGroup `yaml:"dependency_groups,omitempty"`
 
 	Setup struct {
 		BackupDirs  []string `yaml:"backup_dirs"`
@@ -39,6 +44,8 @@ type DotfilesConfig struct {
 	Scripts struct {
 		Install []Script `yaml:"install,omitempty"`
 	} `yaml:"scripts,omitempty"`
+
+	Blocks []Block `yaml:"blocks,omitempty"`
 }
 
 type Link struct {
@@ -46,19 +53,56 @@ type Link struct {
 	Target string `yaml:"target"`
 }
 
+// Expand expands environment variables in the link, $DOTFILES referring
+// to dotfiles
+func (l Link) Expand(dotfiles string) Link {
+	expand := func(s string) string {
+		return os.Expand(s, func(k string) string {
+			if k == "DOTFILES" {
+				return dotfiles
+			}
+			return os.Getenv(k)
+		})
+	}
+	return Link{Source: expand(l.Source), Target: expand(l.Target)}
+}
+
+// Block is content arara manages inside a file it doesn't own, between
+// marker comments (see 'arara setup blocks')
+type Block struct {
+	Name    string `yaml:"name"`
+	File    string `yaml:"file"` // $DOTFILES and env vars are expanded
+	Content string `yaml:"content,omitempty"`
+	Comment string `yaml:"comment,omitempty"` // Such as "//" or "<!-- -->", guessed from the file name by default
+	Absent  bool   `yaml:"absent,omitempty"`  // Remove the block instead
+}
+
 type Step struct {
 	Name        string        `yaml:"name"`
 	Description string        `yaml:"description"`
 	Command     string        `yaml:"command,omitempty"`
 	Commands    []string      `yaml:"commands,omitempty"`
 	Compat      *CompatConfig `yaml:"compat,omitempty"`
+	Privileged  bool          `yaml:"privileged,omitempty"` // Run as root through the configured escalation tool
+
+	Dir string `yaml:"-"` // Dotfiles the step runs in when inherited through extends
 }
 
 type Script struct {
-	Name        string        `yaml:"name"`
-	Description string        `yaml:"description"`
-	Path        string        `yaml:"path"`
-	Compat      *CompatConfig `yaml:"compat,omitempty"`
+	Name         string            `yaml:"name"`
+	Description  string            `yaml:"description"`
+	Path         string            `yaml:"path"`
+	Uninstall    string            `yaml:"uninstall,omitempty"` // Defaults to scripts/uninstall/<name> if present
+	Upgrade      string            `yaml:"upgrade,omitempty"`   // Defaults to scripts/upgrade/<name> if present
+	Args         []string          `yaml:"args,omitempty"`      // Default args when none are given after --
+	Env          map[string]string `yaml:"env,omitempty"`       // Extra env, layered over the namespace env
+	Compat       *CompatConfig     `yaml:"compat,omitempty"`
+	Privileged   bool              `yaml:"privileged,omitempty"`    // Run as root through the configured escalation tool
+	Dependencies []string          `yaml:"dependencies,omitempty"`  // Packages checked by install before running
+	SHA256       string            `yaml:"sha256,omitempty"`        // Pinned hash, verified before running
+	ActionSHA256 map[string]string `yaml:"action_sha256,omitempty"` // Pinned hashes of the uninstall and upgrade actions
+
+	Dir string `yaml:"-"` // Dotfiles path of the base namespace defining an inherited script
 }
 
 // String implements fmt.Stringer for interactive selection
@@ -75,7 +119,9 @@ type CompatConfig struct {
 	Custom []interface{} `yaml:"custom,omitempty"`
 }
 
-func LoadConfig(path string) (*DotfilesConfig, error) {
+// ReadConfig reads the arara.yaml at path without validating its
+// namespace against the global config
+func ReadConfig(path string) (*DotfilesConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
@@ -85,6 +131,14 @@ func LoadConfig(path string) (*DotfilesConfig, error) {
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	return &config, nil
+}
+
+func LoadConfig(path string) (*DotfilesConfig, error) {
+	config, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
 
 	// Only validate namespace if it's a local config and we're not in a test environment
 	if filepath.Base(path) == "arara.yaml" && os.Getenv("TEST_MODE") != "1" {
@@ -109,7 +163,7 @@ func LoadConfig(path string) (*DotfilesConfig, error) {
 		}
 	}
 
-	return &config, nil
+	return config, nil
 }
 
 func GetConfigDir() string {
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Dependency is an entry of the dependencies list in arara.yaml. It is
+// written either as a plain package name ("git", "flatpak:org.mozilla.firefox",
+// "neovim>=0.9") or as an object with a logical name and per-manager or
+// per-distro package names:
+//
+//	dependencies:
+//	  - name: fd
+//	    version: ">=8"
+//	    apt: fd-find
+//	    pacman: fd
+//	    distro:
+//	      ubuntu: fd-find
+//	    default: fd
+type Dependency struct {
+	Name     string            `yaml:"name"`
+	Version  string            `yaml:"version,omitempty"` // Constraint such as ">=0.9" or ">=0.9,<0.11"
+	Default  string            `yaml:"default,omitempty"` // Used when no override matches
+	Distro   map[string]string `yaml:"distro,omitempty"`  // os-release ID or ID_LIKE to package
+	Managers map[string]string `yaml:",inline"`           // package manager to package
+}
+
+// PackageManagers lists the package managers a dependency can map
+// packages for, as supported by 'arara deps'. Other keys of a dependency
+// are errors rather than mappings nothing would use.
+var PackageManagers = []string{
+	"apt", "dnf", "yum", "pacman", "brew", "cask", "zypper", "apk", "xbps", "emerge",
+	"nix", "flatpak", "snap", "cargo", "pipx", "go", "npm",
+}
+
+// CoreGroup is the name of the group formed by the top-level dependencies
+const CoreGroup = "core"
+
+// DependencyGroup is a named set of dependencies in the dependency_groups
+// section of arara.yaml:
+//
+//	dependency_groups:
+//	  gui:
+//	    description: Desktop applications
+//	    optional: true
+//	    compat:
+//	      os: linux
+//	    dependencies:
+//	      - flatpak:org.mozilla.firefox
+type DependencyGroup struct {
+	Description  string        `yaml:"description,omitempty"`
+	Optional     bool          `yaml:"optional,omitempty"` // Only installed when requested
+	Compat       *CompatConfig `yaml:"compat,omitempty"`   // Group is skipped on other systems
+	Dependencies []Dependency  `yaml:"dependencies"`
+}
+
+// UnmarshalYAML accepts both the plain string and the object form
+func (d *Dependency) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*d = ParseDependency(node.Value)
+		return nil
+	}
+	type plain Dependency // avoid recursion
+	var p plain
+	if err := node.Decode(&p); err != nil {
+		return err
+	}
+	if p.Name == "" {
+		return fmt.Errorf("line %d: dependency is missing a name", node.Line)
+	}
+	for key := range p.Managers {
+		if !isManager(key) {
+			return fmt.Errorf("line %d: dependency %s has unknown key %q; use name, version, default, distro or a package manager (%s)",
+				node.Line, p.Name, key, strings.Join(PackageManagers, ", "))
+		}
+	}
+	*d = Dependency(p)
+	return nil
+}
+
+// isManager reports whether name is one of PackageManagers
+func isManager(name string) bool {
+	for _, m := range PackageManagers {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalYAML writes dependencies without overrides as plain strings
+func (d Dependency) MarshalYAML() (interface{}, error) {
+	if !d.HasOverrides() {
+		return d.Name + d.Version, nil
+	}
+	type plain Dependency
+	return plain(d), nil
+}
+
+// constraintOps are the characters that start a version constraint
+const constraintOps = "<>=!"
+
+// ParseDependency parses a plain dependency entry, splitting off a version
+// constraint such as "neovim>=0.9"
+func ParseDependency(entry string) Dependency {
+	name, constraint := SplitConstraint(entry)
+	return Dependency{Name: name, Version: constraint}
+}
+
+// SplitConstraint splits "name>=1.0" into the name and the constraint
+func SplitConstraint(entry string) (name, constraint string) {
+	if i := strings.IndexAny(entry, constraintOps); i > 0 {
+		return strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i:])
+	}
+	return entry, ""
+}
+
+// HasOverrides reports whether the package name depends on the system
+func (d Dependency) HasOverrides() bool {
+	return d.Default != "" || len(d.Distro) > 0 || len(d.Managers) > 0
+}
+
+// Resolve returns the package entry to install for the package manager
+// named manager on a system identified by distros (most specific first).
+// Distro overrides win over manager overrides, which win over the default.
+// Dependencies without overrides resolve to their name. The result may
+// itself carry a "manager:" prefix.
+func (d Dependency) Resolve(manager string, distros []string) (string, error) {
+	if !d.HasOverrides() {
+		return d.Name, nil
+	}
+	for _, distro := range distros {
+		if pkg, ok := d.Distro[distro]; ok {
+			return pkg, nil
+		}
+	}
+	if pkg, ok := d.Managers[manager]; ok {
+		return pkg, nil
+	}
+	if d.Default != "" {
+		return d.Default, nil
+	}
+
+	system := manager
+	if len(distros) > 0 {
+		system += " (" + strings.Join(distros, ", ") + ")"
+	}
+	known := make([]string, 0, len(d.Managers)+len(d.Distro))
+	for name := range d.Managers {
+		known = append(known, name)
+	}
+	for name := range d.Distro {
+		known = append(known, "distro "+name)
+	}
+	sort.Strings(known)
+	return "", fmt.Errorf("no package mapping for %s on %s (mapped for: %s); add a %q or distro entry, or a default",
+		d.Name, system, strings.Join(known, ", "), manager)
+}
+
+// String describes the dependency and its overrides for listings
+func (d Dependency) String() string {
+	if !d.HasOverrides() {
+		return d.Name + d.Version
+	}
+	var overrides []string
+	for name, pkg := range d.Managers {
+		overrides = append(overrides, name+": "+pkg)
+	}
+	for name, pkg := range d.Distro {
+		overrides = append(overrides, "distro "+name+": "+pkg)
+	}
+	sort.Strings(overrides)
+	if d.Default != "" {
+		overrides = append(overrides, "default: "+d.Default)
+	}
+	return fmt.Sprintf("%s%s (%s)", d.Name, d.Version, strings.Join(overrides, ", "))
+}
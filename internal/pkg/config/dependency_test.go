@@ -0,0 +1,138 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDependencyYAML(t *testing.T) {
+	data := `
+dependencies:
+  - git
+  - flatpak:org.mozilla.firefox
+  - name: fd
+    apt: fd-find
+    pacman: fd
+  - name: pip
+    distro:
+      ubuntu: python3-pip
+    default: python-pip
+`
+	var cfg config.DotfilesConfig
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	deps := cfg.Dependencies
+	if len(deps) != 4 {
+		t.Fatalf("Expected 4 dependencies, got %d", len(deps))
+	}
+	if deps[0].Name != "git" || deps[0].HasOverrides() {
+		t.Errorf("Expected plain git, got %+v", deps[0])
+	}
+	if deps[1].Name != "flatpak:org.mozilla.firefox" {
+		t.Errorf("Expected flatpak entry, got %+v", deps[1])
+	}
+	if deps[2].Name != "fd" || deps[2].Managers["apt"] != "fd-find" || deps[2].Managers["pacman"] != "fd" {
+		t.Errorf("Expected fd with manager mappings, got %+v", deps[2])
+	}
+	if _, ok := deps[2].Managers["name"]; ok {
+		t.Error("name must not be treated as a package manager")
+	}
+	if deps[3].Distro["ubuntu"] != "python3-pip" || deps[3].Default != "python-pip" {
+		t.Errorf("Expected pip with distro mapping and default, got %+v", deps[3])
+	}
+
+	// Plain dependencies are written back as strings
+	out, err := cfg.Marshal()
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	for _, want := range []string{"- git\n", "- name: fd\n", "apt: fd-find", "ubuntu: python3-pip"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Expected marshalled config to contain %q:\n%s", want, out)
+		}
+	}
+
+	var bad config.DotfilesConfig
+	if err := yaml.Unmarshal([]byte("dependencies:\n  - apt: fd-find\n"), &bad); err == nil {
+		t.Error("Expected an error for a dependency without a name")
+	}
+	err = yaml.Unmarshal([]byte("dependencies:\n  - name: fd\n    defualt: fd\n"), &bad)
+	if err == nil || !strings.Contains(err.Error(), `unknown key "defualt"`) {
+		t.Errorf("Expected an error for a misspelled key, got %v", err)
+	}
+}
+
+func TestDependencyResolve(t *testing.T) {
+	fd := config.Dependency{
+		Name:     "fd",
+		Managers: map[string]string{"apt": "fd-find", "pacman": "fd"},
+		Distro:   map[string]string{"ubuntu": "fd-find-ubuntu"},
+	}
+
+	testCases := []struct {
+		name    string
+		dep     config.Dependency
+		manager string
+		distros []string
+		want    string
+		wantErr bool
+	}{
+		{"plain", config.Dependency{Name: "git"}, "apt", nil, "git", false},
+		{"distro wins", fd, "apt", []string{"ubuntu", "debian"}, "fd-find-ubuntu", false},
+		{"manager", fd, "apt", []string{"debian"}, "fd-find", false},
+		{"default", config.Dependency{Name: "bat", Default: "bat-cat", Managers: map[string]string{"brew": "bat"}}, "apt", nil, "bat-cat", false},
+		{"no mapping", fd, "dnf", []string{"fedora"}, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.dep.Resolve(tc.manager, tc.distros)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Resolve() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseDependency(t *testing.T) {
+	testCases := []struct {
+		entry       string
+		wantName    string
+		wantVersion string
+	}{
+		{"git", "git", ""},
+		{"neovim>=0.9", "neovim", ">=0.9"},
+		{"neovim>=0.9,<0.11", "neovim", ">=0.9,<0.11"},
+		{"brew:neovim==0.10.1", "brew:neovim", "==0.10.1"},
+		{"go:golang.org/x/tools/gopls@latest", "go:golang.org/x/tools/gopls@latest", ""},
+	}
+	for _, tc := range testCases {
+		dep := config.ParseDependency(tc.entry)
+		if dep.Name != tc.wantName || dep.Version != tc.wantVersion {
+			t.Errorf("ParseDependency(%q) = %q %q, want %q %q", tc.entry, dep.Name, dep.Version, tc.wantName, tc.wantVersion)
+		}
+		if dep.String() != tc.entry {
+			t.Errorf("String() = %q, want %q", dep.String(), tc.entry)
+		}
+	}
+
+	var cfg config.DotfilesConfig
+	if err := yaml.Unmarshal([]byte("dependencies:\n  - neovim>=0.9\n"), &cfg); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if cfg.Dependencies[0].Name != "neovim" || cfg.Dependencies[0].Version != ">=0.9" {
+		t.Errorf("Expected constraint to be split off, got %+v", cfg.Dependencies[0])
+	}
+	out, _ := cfg.Marshal()
+	if !strings.Contains(string(out), "- neovim>=0.9") {
+		t.Errorf("Expected constraint to be written back inline:\n%s", out)
+	}
+}
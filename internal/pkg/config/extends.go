@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// LoadMergedConfig loads the arara.yaml at path layered over the
+// namespaces it extends. Links, build steps, scripts, blocks, dependencies,
+// backup dirs and env of the base are merged in, and entries defined
+// locally win.
+// Use LoadConfig instead to edit the file, which must not gain the base's
+// entries.
+func LoadMergedConfig(path string) (*DotfilesConfig, error) {
+	return loadMerged(path, nil)
+}
+
+// loadMerged loads path and its bases, seen holds the namespaces of the
+// chain so far to refuse cycles
+func loadMerged(path string, seen []string) (*DotfilesConfig, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Extends == "" {
+		return cfg, nil
+	}
+
+	for _, ns := range seen {
+		if ns == cfg.Extends {
+			return nil, fmt.Errorf("namespace cycle: %s -> %s", strings.Join(seen, " -> "), cfg.Extends)
+		}
+	}
+
+	gc, err := NewGlobalConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load global config: %w", err)
+	}
+	info, ok := gc.Configs[cfg.Extends]
+	if !ok {
+		return nil, fmt.Errorf("%s extends unknown namespace: %s", path, cfg.Extends)
+	}
+
+	// The chain starts at the namespace of path, if it is registered
+	if len(seen) == 0 {
+		if ns, ok := gc.NamespaceAt(filepath.Dir(path)); ok {
+			seen = []string{ns}
+		}
+	}
+	base, err := loadMerged(filepath.Join(info.Path, "arara.yaml"), append(seen, cfg.Extends))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base namespace %s: %w", cfg.Extends, err)
+	}
+	return Merge(base, cfg, info.Path), nil
+}
+
+// Merge layers local over base, whose dotfiles are at baseDir. Entries are
+// matched by name, links by target, blocks by name and file, and env by
+// key; a local entry replaces the base one in place and new local entries
+// follow the base ones. $DOTFILES in base links and block files is
+// expanded to baseDir and inherited scripts and build steps remember
+// baseDir, so they keep pointing into the base repository.
+func Merge(base, local *DotfilesConfig, baseDir string) *DotfilesConfig {
+	merged := *local
+
+	merged.Env = make(map[string]string)
+	for k, v := range base.Env {
+		merged.Env[k] = v
+	}
+	for k, v := range local.Env {
+		merged.Env[k] = v
+	}
+
+	merged.Dependencies = mergeDependencies(base.Dependencies, local.Dependencies)
+	merged.DependencyGroups = make(map[string]DependencyGroup)
+	for name, g := range base.DependencyGroups {
+		merged.DependencyGroups[name] = g
+	}
+	for name, g := range local.DependencyGroups {
+		if b, ok := base.DependencyGroups[name]; ok {
+			g.Dependencies = mergeDependencies(b.Dependencies, g.Dependencies)
+			if g.Description == "" {
+				g.Description = b.Description
+			}
+		}
+		merged.DependencyGroups[name] = g
+	}
+
+	merged.Setup.BackupDirs = mergeBy(base.Setup.BackupDirs, local.Setup.BackupDirs,
+		func(dir string) string { return dir })
+	linkTarget := func(l Link) string { return l.Target }
+	merged.Setup.CoreLinks = mergeBy(baseLinks(base.Setup.CoreLinks, baseDir), local.Setup.CoreLinks, linkTarget)
+	merged.Setup.ConfigLinks = mergeBy(baseLinks(base.Setup.ConfigLinks, baseDir), local.Setup.ConfigLinks, linkTarget)
+
+	baseSteps := make([]Step, len(base.Build.Steps))
+	for i, s := range base.Build.Steps {
+		if s.Dir == "" {
+			s.Dir = baseDir
+		}
+		baseSteps[i] = s
+	}
+	merged.Build.Steps = mergeBy(baseSteps, local.Build.Steps,
+		func(s Step) string { return s.Name })
+
+	inherited := make([]Script, len(base.Scripts.Install))
+	for i, s := range base.Scripts.Install {
+		if s.Dir == "" {
+			s.Dir = baseDir
+		}
+		inherited[i] = s
+	}
+	merged.Scripts.Install = mergeBy(inherited, local.Scripts.Install,
+		func(s Script) string { return s.Name })
+
+	baseBlocks := make([]Block, len(base.Blocks))
+	for i, b := range base.Blocks {
+		b.File = expandDotfiles(b.File, baseDir)
+		baseBlocks[i] = b
+	}
+	merged.Blocks = mergeBy(baseBlocks, local.Blocks,
+		func(b Block) string { return b.Name + "\x00" + b.File })
+
+	return &merged
+}
+
+// mergeDependencies merges dependencies by name, local ones win
+func mergeDependencies(base, local []Dependency) []Dependency {
+	return mergeBy(base, local, func(d Dependency) string { return d.Name })
+}
+
+// mergeBy returns base with entries replaced by the local entry of the
+// same key, followed by the remaining local entries
+func mergeBy[T any](base, local []T, key func(T) string) []T {
+	if len(base) == 0 {
+		return local
+	}
+	byKey := make(map[string]int)
+	for i, v := range local {
+		byKey[key(v)] = i
+	}
+
+	merged := make([]T, 0, len(base)+len(local))
+	used := make(map[int]bool)
+	for _, v := range base {
+		if i, ok := byKey[key(v)]; ok {
+			if !used[i] {
+				merged = append(merged, local[i])
+				used[i] = true
+			}
+			continue
+		}
+		merged = append(merged, v)
+	}
+	for i, v := range local {
+		if !used[i] {
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+// baseLinks expands $DOTFILES in the sources of links to dir
+func baseLinks(links []Link, dir string) []Link {
+	out := make([]Link, len(links))
+	for i, l := range links {
+		l.Source = expandDotfiles(l.Source, dir)
+		out[i] = l
+	}
+	return out
+}
+
+// expandDotfiles expands $DOTFILES in s to dir
+func expandDotfiles(s, dir string) string {
+	return strings.NewReplacer("${DOTFILES}", dir, "$DOTFILES", dir).Replace(s)
+}
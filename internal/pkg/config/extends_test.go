@@ -0,0 +1,175 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+)
+
+func TestLoadMergedConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+
+	team := filepath.Join(tmpDir, "team")
+	personal := filepath.Join(tmpDir, "personal")
+	files := map[string]string{
+		team: `
+name: team
+env:
+  EDITOR: vim
+  PAGER: less
+dependencies:
+  - name: git
+  - name: fd
+    apt: fd-find
+setup:
+  backup_dirs: ["$HOME/.config"]
+  core_links:
+    - source: "$DOTFILES/.config"
+      target: "$HOME/.config"
+  config_links:
+    - source: "$DOTFILES/.bashrc"
+      target: "$HOME/.bashrc"
+build:
+  steps:
+    - name: fonts
+      command: fc-cache
+scripts:
+  install:
+    - name: docker
+      path: scripts/install/docker
+    - name: neovim
+      path: scripts/install/neovim
+blocks:
+  - name: aliases
+    file: $HOME/.bashrc
+    content: source $DOTFILES/aliases.sh
+  - name: colors
+    file: $DOTFILES/colors.conf
+`,
+		personal: `
+name: personal
+extends: team
+env:
+  EDITOR: nvim
+dependencies:
+  - name: fd
+  - name: zsh
+setup:
+  config_links:
+    - source: "$DOTFILES/.bashrc"
+      target: "$HOME/.bashrc"
+    - source: "$DOTFILES/.zshrc"
+      target: "$HOME/.zshrc"
+scripts:
+  install:
+    - name: neovim
+      path: scripts/install/nvim-nightly
+blocks:
+  - name: aliases
+    file: $HOME/.bashrc
+    content: source ~/.aliases
+`,
+	}
+	for dir, content := range files {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "arara.yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ns := range []string{"team", "personal"} {
+		if err := gc.AddNamespace(ns, filepath.Join(tmpDir, ns), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := config.LoadMergedConfig(filepath.Join(personal, "arara.yaml"))
+	if err != nil {
+		t.Fatalf("LoadMergedConfig() error = %v", err)
+	}
+
+	if cfg.Name != "personal" || cfg.Env["EDITOR"] != "nvim" || cfg.Env["PAGER"] != "less" {
+		t.Errorf("name and env = %s %v, want personal with EDITOR=nvim PAGER=less", cfg.Name, cfg.Env)
+	}
+
+	var deps []string
+	for _, d := range cfg.Dependencies {
+		deps = append(deps, d.Name)
+	}
+	if got := strings.Join(deps, " "); got != "git fd zsh" {
+		t.Errorf("dependencies = %q, want %q", got, "git fd zsh")
+	}
+	if cfg.Dependencies[1].HasOverrides() {
+		t.Errorf("local fd should replace the base mapping, got %+v", cfg.Dependencies[1])
+	}
+
+	if len(cfg.Setup.BackupDirs) != 1 || len(cfg.Build.Steps) != 1 || cfg.Build.Steps[0].Name != "fonts" {
+		t.Errorf("expected base backup dirs and steps, got %v %+v", cfg.Setup.BackupDirs, cfg.Build.Steps)
+	} else if cfg.Build.Steps[0].Dir != team {
+		t.Errorf("fonts should run from %s, got %q", team, cfg.Build.Steps[0].Dir)
+	}
+	if len(cfg.Setup.CoreLinks) != 1 || cfg.Setup.CoreLinks[0].Source != filepath.Join(team, ".config") {
+		t.Errorf("expected base core link into %s, got %+v", team, cfg.Setup.CoreLinks)
+	}
+	wantLinks := []config.Link{
+		{Source: "$DOTFILES/.bashrc", Target: "$HOME/.bashrc"},
+		{Source: "$DOTFILES/.zshrc", Target: "$HOME/.zshrc"},
+	}
+	if len(cfg.Setup.ConfigLinks) != 2 || cfg.Setup.ConfigLinks[0] != wantLinks[0] || cfg.Setup.ConfigLinks[1] != wantLinks[1] {
+		t.Errorf("config links = %+v, want %+v", cfg.Setup.ConfigLinks, wantLinks)
+	}
+
+	if len(cfg.Scripts.Install) != 2 {
+		t.Fatalf("expected 2 scripts, got %+v", cfg.Scripts.Install)
+	}
+	if s := cfg.Scripts.Install[0]; s.Name != "docker" || s.Dir != team {
+		t.Errorf("docker should be inherited from %s, got %+v", team, s)
+	}
+	if s := cfg.Scripts.Install[1]; s.Path != "scripts/install/nvim-nightly" || s.Dir != "" {
+		t.Errorf("neovim should be the local script, got %+v", s)
+	}
+
+	if len(cfg.Blocks) != 2 || cfg.Blocks[0].Content != "source ~/.aliases" || cfg.Blocks[1].File != filepath.Join(team, "colors.conf") {
+		t.Errorf("expected the local aliases block and the base colors block in %s, got %+v", team, cfg.Blocks)
+	}
+
+	// The file itself is left as written, so editing it keeps it local
+	local, err := config.LoadConfig(filepath.Join(personal, "arara.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(local.Dependencies) != 2 || len(local.Scripts.Install) != 1 {
+		t.Errorf("LoadConfig() should not merge, got %+v", local)
+	}
+
+	t.Run("Cycle", func(t *testing.T) {
+		content, _ := os.ReadFile(filepath.Join(team, "arara.yaml"))
+		if err := os.WriteFile(filepath.Join(team, "arara.yaml"), append(content, "extends: personal\n"...), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := config.LoadMergedConfig(filepath.Join(personal, "arara.yaml"))
+		if err == nil || !strings.Contains(err.Error(), "namespace cycle: personal -> team -> personal") {
+			t.Errorf("expected a cycle error, got %v", err)
+		}
+	})
+
+	t.Run("UnknownBase", func(t *testing.T) {
+		path := filepath.Join(tmpDir, "orphan.yaml")
+		if err := os.WriteFile(path, []byte("extends: missing\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := config.LoadMergedConfig(path); err == nil || !strings.Contains(err.Error(), "unknown namespace: missing") {
+			t.Errorf("expected unknown namespace error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BuddhiLW/arara/internal/pkg/vars"
+	bonzaiVars "github.com/rwxrob/bonzai/vars"
+)
+
+// Sources of the active namespace, from highest to lowest precedence
+const (
+	FromEnv       = "env"       // $ARARA_ACTIVE_NAMESPACE
+	FromDirectory = "directory" // arara.yaml above the working directory
+	FromSwitch    = "switch"    // 'arara namespace switch'
+	FromDefault   = "default"   // first registered namespace
+)
+
+// Resolution is the active namespace and where the choice came from
+type Resolution struct {
+	Name       string
+	Path       string // dotfiles path, empty if the namespace is unknown
+	Source     string // one of the From constants, empty if none applies
+	ConfigPath string // closest arara.yaml above the working directory
+}
+
+// Explain describes where the namespace came from
+func (r Resolution) Explain() string {
+	switch r.Source {
+	case FromEnv:
+		return fmt.Sprintf("set by $%s", vars.ActiveNamespaceEnv)
+	case FromDirectory:
+		return fmt.Sprintf("found %s", r.ConfigPath)
+	case FromSwitch:
+		return "set by 'arara namespace switch'"
+	case FromDefault:
+		return "first registered namespace"
+	}
+	return "no namespace registered"
+}
+
+// active is the resolution made by ActivateNamespace
+var active *Resolution
+
+// FindConfig walks up from dir to the closest arara.yaml, like git looks
+// for .git. Returns false if there is none up to the root.
+func FindConfig(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, "arara.yaml")
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// NamespaceAt returns the registered namespace whose dotfiles path is dir
+func (gc *GlobalConfig) NamespaceAt(dir string) (string, bool) {
+	for _, ns := range gc.Namespaces {
+		if info, ok := gc.Configs[ns]; ok && samePath(info.Path, dir) {
+			return ns, true
+		}
+	}
+	return "", false
+}
+
+// ResolveNamespace determines the active namespace for the working
+// directory dir: $ARARA_ACTIVE_NAMESPACE if set, else the registered
+// namespace of the closest arara.yaml above dir, else the namespace
+// chosen with 'arara namespace switch', else the first registered one.
+func (gc *GlobalConfig) ResolveNamespace(dir string) Resolution {
+	var r Resolution
+	var dirNS string
+	if path, ok := FindConfig(dir); ok {
+		r.ConfigPath = path
+		dirNS, _ = gc.NamespaceAt(filepath.Dir(path))
+	}
+
+	switch {
+	case os.Getenv(vars.ActiveNamespaceEnv) != "":
+		r.Name, r.Source = os.Getenv(vars.ActiveNamespaceEnv), FromEnv
+	case dirNS != "":
+		r.Name, r.Source = dirNS, FromDirectory
+	case switched() != "":
+		r.Name, r.Source = switched(), FromSwitch
+	case len(gc.Namespaces) > 0:
+		r.Name, r.Source = gc.Namespaces[0], FromDefault
+	}
+
+	if info, ok := gc.Configs[r.Name]; ok {
+		r.Path = info.Path
+	}
+	return r
+}
+
+// ActivateNamespace resolves the namespace for dir and makes it the
+// active one for this process and the commands it runs. A namespace found
+// from the directory is not persisted, so it only applies inside it.
+func (gc *GlobalConfig) ActivateNamespace(dir string) Resolution {
+	r := gc.ResolveNamespace(dir)
+	active = &r
+	if r.Source == FromDirectory {
+		os.Setenv(vars.ActiveNamespaceEnv, r.Name)
+		os.Setenv(vars.DotfilesPathEnv, r.Path)
+	}
+	return r
+}
+
+// ActiveResolution returns the resolution made by ActivateNamespace when
+// arara started, or resolves it for dir
+func (gc *GlobalConfig) ActiveResolution(dir string) Resolution {
+	if active != nil {
+		return *active
+	}
+	return gc.ResolveNamespace(dir)
+}
+
+// switched returns the namespace persisted by 'arara namespace switch'
+func switched() string {
+	ns, err := bonzaiVars.Data.Get(vars.ActiveNamespaceVar)
+	if err != nil {
+		return ""
+	}
+	return ns
+}
+
+// samePath reports whether a and b name the same directory, following
+// symlinks
+func samePath(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if ra, err := filepath.EvalSymlinks(a); err == nil {
+		a = ra
+	}
+	if rb, err := filepath.EvalSymlinks(b); err == nil {
+		b = rb
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
@@ -0,0 +1,135 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+	bonzaiVars "github.com/rwxrob/bonzai/vars"
+)
+
+func TestResolveNamespace(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+	t.Setenv("ARARA_ACTIVE_NAMESPACE", "")
+	defer bonzaiVars.Data.Clear()
+	bonzaiVars.Data.Clear()
+
+	// Two registered repositories and one that isn't registered
+	work := filepath.Join(tmpDir, "work")
+	home := filepath.Join(tmpDir, "home")
+	stray := filepath.Join(tmpDir, "stray")
+	for _, dir := range []string{filepath.Join(work, "scripts", "install"), home, stray} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range []string{work, home, stray} {
+		if err := os.WriteFile(filepath.Join(dir, "arara.yaml"), []byte("name: test\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.AddNamespace("home", home, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := gc.AddNamespace("work", work, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		dir        string
+		env        string
+		switched   string
+		wantName   string
+		wantSource string
+		wantConfig string
+	}{
+		{
+			name:       "Directory",
+			dir:        filepath.Join(work, "scripts", "install"),
+			wantName:   "work",
+			wantSource: config.FromDirectory,
+			wantConfig: filepath.Join(work, "arara.yaml"),
+		},
+		{
+			name:       "EnvWins",
+			dir:        work,
+			env:        "home",
+			wantName:   "home",
+			wantSource: config.FromEnv,
+			wantConfig: filepath.Join(work, "arara.yaml"),
+		},
+		{
+			name:       "DirectoryOverSwitch",
+			dir:        home,
+			switched:   "work",
+			wantName:   "home",
+			wantSource: config.FromDirectory,
+			wantConfig: filepath.Join(home, "arara.yaml"),
+		},
+		{
+			name:       "UnregisteredDirectory",
+			dir:        stray,
+			switched:   "work",
+			wantName:   "work",
+			wantSource: config.FromSwitch,
+			wantConfig: filepath.Join(stray, "arara.yaml"),
+		},
+		{
+			name:       "Default",
+			dir:        tmpDir,
+			wantName:   "home",
+			wantSource: config.FromDefault,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ARARA_ACTIVE_NAMESPACE", tt.env)
+			bonzaiVars.Data.Clear()
+			if tt.switched != "" {
+				bonzaiVars.Data.Set("active-namespace", tt.switched)
+			}
+
+			r := gc.ResolveNamespace(tt.dir)
+			if r.Name != tt.wantName || r.Source != tt.wantSource || r.ConfigPath != tt.wantConfig {
+				t.Errorf("ResolveNamespace(%s) = %+v, want %s from %s (%s)", tt.dir, r, tt.wantName, tt.wantSource, tt.wantConfig)
+			}
+			if info := gc.Configs[tt.wantName]; r.Path != info.Path {
+				t.Errorf("Path = %q, want %q", r.Path, info.Path)
+			}
+			if r.Explain() == "" {
+				t.Error("Explain() is empty")
+			}
+		})
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	nested := filepath.Join(tmpDir, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// An arara.yaml above the temp dir may exist, but none inside it
+	if path, ok := config.FindConfig(nested); ok && strings.HasPrefix(path, tmpDir) {
+		t.Errorf("FindConfig found %s before it exists", path)
+	}
+
+	configPath := filepath.Join(tmpDir, "a", "arara.yaml")
+	if err := os.WriteFile(configPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if path, ok := config.FindConfig(nested); !ok || path != configPath {
+		t.Errorf("FindConfig(%s) = %q, %v, want %q", nested, path, ok, configPath)
+	}
+}
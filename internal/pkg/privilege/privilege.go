@@ -0,0 +1,195 @@
+package privilege
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/BuddhiLW/arara/internal/pkg/config"
+	"github.com/BuddhiLW/arara/internal/pkg/vars"
+	bonzaiVars "github.com/rwxrob/bonzai/vars"
+)
+
+// None runs commands unchanged, for when arara already runs as root
+const None = "none"
+
+// Auto picks None as root or else the first installed tool of Tools
+const Auto = "auto"
+
+// Tools lists the supported escalation tools in detection order
+var Tools = []string{"sudo", "doas", "run0", "pkexec"}
+
+// keepAliveInterval is how often cached sudo credentials are refreshed,
+// well below the default five minute timestamp timeout
+const keepAliveInterval = time.Minute
+
+// Test hooks
+var (
+	geteuid  = os.Geteuid
+	lookPath = exec.LookPath
+	runCmd   = func(args ...string) error {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+)
+
+// Session escalates commands with one tool. Credentials are validated
+// once when the session begins and, for sudo, kept alive until End.
+type Session struct {
+	Tool string // one of Tools, or None
+	stop chan struct{}
+}
+
+// Configured returns the escalation setting: the privilege var
+// (ARARA_PRIVILEGE) if set, else privilege in the global config, else Auto
+func Configured() (string, error) {
+	if v := bonzaiVars.Fetch(vars.PrivilegeEnv, vars.PrivilegeVar, ""); v != "" {
+		return v, nil
+	}
+	gc, err := config.NewGlobalConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to load global config: %w", err)
+	}
+	if gc.Privilege != "" {
+		return gc.Privilege, nil
+	}
+	return Auto, nil
+}
+
+// Resolve returns the tool for setting, detecting one for Auto
+func Resolve(setting string) (string, error) {
+	switch setting {
+	case "", Auto:
+		if geteuid() == 0 {
+			return None, nil
+		}
+		for _, tool := range Tools {
+			if _, err := lookPath(tool); err == nil {
+				return tool, nil
+			}
+		}
+		return "", fmt.Errorf("no privilege escalation tool found (tried %s)", strings.Join(Tools, ", "))
+	case None:
+		return None, nil
+	}
+
+	for _, tool := range Tools {
+		if setting == tool {
+			if _, err := lookPath(tool); err != nil {
+				return "", fmt.Errorf("privilege escalation tool %s not found", tool)
+			}
+			return tool, nil
+		}
+	}
+	return "", fmt.Errorf("unknown privilege escalation tool: %s (supported: %s, %s, %s)",
+		setting, strings.Join(Tools, ", "), None, Auto)
+}
+
+// Begin resolves the configured tool and validates credentials once, so
+// the password is asked for up front rather than midway through a run
+func Begin() (*Session, error) {
+	setting, err := Configured()
+	if err != nil {
+		return nil, err
+	}
+	tool, err := Resolve(setting)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{Tool: tool}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	s.keepAlive()
+	return s, nil
+}
+
+// shared is the session of the current run
+var shared *Session
+
+// Shared returns the session shared by every privileged command of the
+// run, beginning it on first use. It is kept alive until the process exits.
+func Shared() (*Session, error) {
+	if shared == nil {
+		s, err := Begin()
+		if err != nil {
+			return nil, err
+		}
+		shared = s
+	}
+	return shared, nil
+}
+
+// validate prompts for credentials where the tool can cache them
+func (s *Session) validate() error {
+	var err error
+	switch s.Tool {
+	case "sudo":
+		err = runCmd("sudo", "-v")
+	case "doas":
+		// Caches credentials when doas.conf allows "persist"
+		err = runCmd("doas", "true")
+	default:
+		// run0 and pkexec authenticate every command through polkit
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to validate %s credentials: %w", s.Tool, err)
+	}
+	return nil
+}
+
+// keepAlive refreshes cached sudo credentials until End so long builds
+// don't prompt again
+func (s *Session) keepAlive() {
+	if s.Tool != "sudo" {
+		return
+	}
+	stop := make(chan struct{})
+	s.stop = stop
+	go func() {
+		ticker := time.NewTicker(keepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// Non-interactive: never prompt from the background
+				runCmd("sudo", "-n", "-v")
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// End stops keeping credentials alive
+func (s *Session) End() {
+	if s != nil && s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// Command returns args prefixed with the escalation tool
+func (s *Session) Command(args ...string) []string {
+	if s.Tool == None || s.Tool == "" {
+		return args
+	}
+	return append([]string{s.Tool}, args...)
+}
+
+// CommandEnv is like Command but passes env (KEY=value) through env(1),
+// since escalation tools reset the environment
+func (s *Session) CommandEnv(env []string, args ...string) []string {
+	if s.Tool == None || s.Tool == "" || len(env) == 0 {
+		return s.Command(args...)
+	}
+	cmd := append([]string{"env"}, env...)
+	return s.Command(append(cmd, args...)...)
+}
@@ -0,0 +1,107 @@
+package privilege
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	origEuid, origLook := geteuid, lookPath
+	defer func() { geteuid, lookPath = origEuid, origLook }()
+
+	testCases := []struct {
+		name      string
+		setting   string
+		euid      int
+		installed []string
+		want      string
+		wantErr   bool
+	}{
+		{name: "root", setting: Auto, euid: 0, installed: []string{"sudo"}, want: None},
+		{name: "auto prefers sudo", setting: "", euid: 1000, installed: []string{"doas", "sudo"}, want: "sudo"},
+		{name: "auto falls back", setting: Auto, euid: 1000, installed: []string{"run0"}, want: "run0"},
+		{name: "auto without tools", setting: Auto, euid: 1000, wantErr: true},
+		{name: "explicit", setting: "doas", euid: 1000, installed: []string{"sudo", "doas"}, want: "doas"},
+		{name: "explicit missing", setting: "pkexec", euid: 1000, installed: []string{"sudo"}, wantErr: true},
+		{name: "none", setting: None, euid: 1000, want: None},
+		{name: "unknown", setting: "su", euid: 1000, installed: []string{"su"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			geteuid = func() int { return tc.euid }
+			lookPath = func(file string) (string, error) {
+				for _, tool := range tc.installed {
+					if tool == file {
+						return "/usr/bin/" + file, nil
+					}
+				}
+				return "", errors.New("not found")
+			}
+
+			got, err := Resolve(tc.setting)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Resolve(%q) = %q, want error", tc.setting, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tc.setting, err)
+			}
+			if got != tc.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tc.setting, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var got []string
+	origRun := runCmd
+	runCmd = func(args ...string) error {
+		got = append(got, strings.Join(args, " "))
+		return nil
+	}
+	defer func() { runCmd = origRun }()
+
+	for tool, want := range map[string]string{"sudo": "sudo -v", "doas": "doas true", "pkexec": "", None: ""} {
+		got = nil
+		s := &Session{Tool: tool}
+		if err := s.validate(); err != nil {
+			t.Fatalf("%s: validate() error = %v", tool, err)
+		}
+		if strings.Join(got, "\n") != want {
+			t.Errorf("%s: ran %q, want %q", tool, got, want)
+		}
+	}
+
+	runCmd = func(args ...string) error { return errors.New("wrong password") }
+	if err := (&Session{Tool: "sudo"}).validate(); err == nil {
+		t.Error("expected error when validation fails")
+	}
+}
+
+func TestCommand(t *testing.T) {
+	env := []string{"ARARA_OS=linux"}
+	testCases := []struct {
+		tool    string
+		want    string
+		wantEnv string
+	}{
+		{"sudo", "sudo apt-get install git", "sudo env ARARA_OS=linux ./script"},
+		{"run0", "run0 apt-get install git", "run0 env ARARA_OS=linux ./script"},
+		{None, "apt-get install git", "./script"},
+	}
+
+	for _, tc := range testCases {
+		s := &Session{Tool: tc.tool}
+		if got := strings.Join(s.Command("apt-get", "install", "git"), " "); got != tc.want {
+			t.Errorf("%s: Command() = %q, want %q", tc.tool, got, tc.want)
+		}
+		if got := strings.Join(s.CommandEnv(env, "./script"), " "); got != tc.wantEnv {
+			t.Errorf("%s: CommandEnv() = %q, want %q", tc.tool, got, tc.wantEnv)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package runlog
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rwxrob/bonzai/futil"
+	"gopkg.in/yaml.v3"
+)
+
+// Run holds the metadata of a single install or build execution
+type Run struct {
+	ID        string    `yaml:"id"`
+	Kind      string    `yaml:"kind"` // install, uninstall, upgrade or build
+	Script    string    `yaml:"script"`
+	Path      string    `yaml:"path,omitempty"`
+	Hash      string    `yaml:"hash,omitempty"` // sha256 of the script file
+	Namespace string    `yaml:"namespace,omitempty"`
+	Host      string    `yaml:"host"`
+	Start     time.Time `yaml:"start"`
+	End       time.Time `yaml:"end"`
+	ExitCode  int       `yaml:"exit_code"`
+	Error     string    `yaml:"error,omitempty"`
+
+	log *os.File
+}
+
+// Dir returns the directory holding run logs, under the user state dir
+func Dir() (string, error) {
+	stateDir, err := futil.UserStateDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get state directory: %w", err)
+	}
+	return filepath.Join(stateDir, "arara", "runs"), nil
+}
+
+// Begin records the start of a run and opens its log file. The caller
+// must call Finish when the run is done.
+func Begin(kind, script, path, namespace string) (*Run, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create runs directory: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	run := &Run{
+		Kind:      kind,
+		Script:    script,
+		Path:      path,
+		Namespace: namespace,
+		Host:      host,
+		Start:     time.Now(),
+	}
+
+	if path != "" {
+		if hash, err := FileHash(path); err == nil {
+			run.Hash = hash
+		}
+	}
+
+	// Run IDs sort chronologically; add a counter on collision
+	base := run.Start.Format("20060102-150405") + "-" + sanitize(script)
+	run.ID = base
+	for i := 2; futil.Exists(filepath.Join(dir, run.ID+".log")); i++ {
+		run.ID = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	f, err := os.Create(filepath.Join(dir, run.ID+".log"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create run log: %w", err)
+	}
+	run.log = f
+
+	return run, nil
+}
+
+// Log returns the writer for the run's captured output
+func (r *Run) Log() io.Writer {
+	return r.log
+}
+
+// Finish records the end time and exit code derived from err, closes the
+// log file and writes the run metadata
+func (r *Run) Finish(runErr error) error {
+	r.End = time.Now()
+	r.ExitCode = ExitCode(runErr)
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+
+	if r.log != nil {
+		r.log.Close()
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, r.ID+".yaml"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write run metadata: %w", err)
+	}
+	return nil
+}
+
+// Duration returns how long the run took
+func (r Run) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// List returns all recorded runs, newest first
+func List() ([]Run, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read runs directory: %w", err)
+	}
+
+	var runs []Run
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".yaml")
+		if !ok {
+			continue
+		}
+		run, err := Load(id)
+		if err != nil {
+			continue
+		}
+		runs = append(runs, *run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].Start.After(runs[j].Start)
+	})
+	return runs, nil
+}
+
+// Load reads the metadata of the run with the given ID
+func Load(id string) (*Run, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, id+".yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run not found: %s", id)
+		}
+		return nil, fmt.Errorf("failed to read run metadata: %w", err)
+	}
+
+	var run Run
+	if err := yaml.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse run metadata: %w", err)
+	}
+	return &run, nil
+}
+
+// LogPath returns the path of the captured output for the run
+func LogPath(id string) (string, error) {
+	if err := checkID(id); err != nil {
+		return "", err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".log"), nil
+}
+
+// ExitCode returns the process exit code carried by err, 0 for nil and -1
+// when the process did not exit normally
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// FileHash returns the hex encoded SHA-256 of the file at path
+func FileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// checkID rejects run IDs that would resolve outside the runs directory,
+// such as ../../x given to 'arara logs'
+func checkID(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("invalid run id: %q", id)
+	}
+	return nil
+}
+
+// sanitize makes name safe for use in a file name
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == os.PathSeparator || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,121 @@
+package runlog_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/runlog"
+)
+
+func TestBeginFinish(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", tmpDir)
+
+	script := filepath.Join(tmpDir, "script")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexit 3\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := runlog.Begin("install", "script", script, "test")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	fmt.Fprintln(run.Log(), "captured output")
+
+	runErr := exec.Command(script).Run()
+	if err := run.Finish(runErr); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	loaded, err := runlog.Load(run.ID)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", loaded.ExitCode)
+	}
+	if loaded.Script != "script" || loaded.Namespace != "test" || loaded.Kind != "install" {
+		t.Errorf("unexpected metadata: %+v", loaded)
+	}
+	want, _ := runlog.FileHash(script)
+	if loaded.Hash == "" || loaded.Hash != want {
+		t.Errorf("Hash = %q, want %q", loaded.Hash, want)
+	}
+
+	logPath, err := runlog.LogPath(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "captured output\n" {
+		t.Errorf("log = %q", data)
+	}
+}
+
+func TestList(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	// No runs yet
+	runs, err := runlog.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("List() = %d runs, want 0", len(runs))
+	}
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		run, err := runlog.Begin("install", "same", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := run.Finish(nil); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, run.ID)
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("run IDs should be unique, got %q twice", ids[0])
+	}
+
+	runs, err = runlog.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("List() = %d runs, want 2", len(runs))
+	}
+	if runs[0].ID != ids[1] {
+		t.Errorf("List() should return newest first, got %s", runs[0].ID)
+	}
+
+	if _, err := runlog.Load("nonexistent"); err == nil {
+		t.Error("Load() should fail for unknown run")
+	}
+	for _, id := range []string{"../../x", "..", "a/b"} {
+		if _, err := runlog.Load(id); err == nil || !strings.Contains(err.Error(), "invalid run id") {
+			t.Errorf("Load(%q) error = %v, want invalid run id", id, err)
+		}
+		if _, err := runlog.LogPath(id); err == nil {
+			t.Errorf("LogPath(%q) should fail", id)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if got := runlog.ExitCode(nil); got != 0 {
+		t.Errorf("ExitCode(nil) = %d, want 0", got)
+	}
+	if got := runlog.ExitCode(errors.New("boom")); got != -1 {
+		t.Errorf("ExitCode(non-exit error) = %d, want -1", got)
+	}
+}
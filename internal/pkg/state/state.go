@@ -0,0 +1,197 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rwxrob/bonzai/persisters/inyaml"
+	"gopkg.in/yaml.v3"
+)
+
+// State represents what arara has done on this machine. Unlike the global
+// config it is never meant to be edited or shared.
+type State struct {
+	Data
+	persister *inyaml.Persister
+}
+
+// Data is the persisted part of State
+type Data struct {
+	// Scripts maps namespace to script name to its install record
+	Scripts map[string]map[string]ScriptRecord `yaml:"scripts,omitempty"`
+
+	// Packages maps namespace to "manager:package" to the dependency
+	// arara installed for it
+	Packages map[string]map[string]PackageRecord `yaml:"packages,omitempty"`
+}
+
+// ScriptRecord describes an installed script
+type ScriptRecord struct {
+	Hash        string    `yaml:"hash,omitempty"` // sha256 of the script that installed it
+	InstalledAt time.Time `yaml:"installed_at"`
+	UpgradedAt  time.Time `yaml:"upgraded_at,omitempty"`
+}
+
+// PackageRecord describes a package arara installed as a dependency
+type PackageRecord struct {
+	Name        string    `yaml:"name"` // logical name from arara.yaml
+	Manager     string    `yaml:"manager"`
+	Package     string    `yaml:"package"`
+	InstalledAt time.Time `yaml:"installed_at"`
+}
+
+// Key identifies the package across namespaces
+func (r PackageRecord) Key() string {
+	return r.Manager + ":" + r.Package
+}
+
+// Load reads the local state from $XDG_STATE_HOME/arara/state.yaml
+var Load = func() (*State, error) {
+	s := &State{
+		persister: inyaml.NewUserState("arara", "state.yaml"),
+		Data: Data{
+			Scripts:  make(map[string]map[string]ScriptRecord),
+			Packages: make(map[string]map[string]PackageRecord),
+		},
+	}
+
+	data := s.persister.Get("state")
+	if data == "" {
+		return s, nil
+	}
+
+	if err := yaml.Unmarshal([]byte(data), &s.Data); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	if s.Scripts == nil {
+		s.Scripts = make(map[string]map[string]ScriptRecord)
+	}
+	if s.Packages == nil {
+		s.Packages = make(map[string]map[string]PackageRecord)
+	}
+
+	return s, nil
+}
+
+// Save persists the local state
+func (s *State) Save() error {
+	data, err := yaml.Marshal(s.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	s.persister.Set("state", string(data))
+	return nil
+}
+
+// MarkInstalled records that script was installed in namespace ns
+func (s *State) MarkInstalled(ns, script, hash string) {
+	if s.Scripts[ns] == nil {
+		s.Scripts[ns] = make(map[string]ScriptRecord)
+	}
+	s.Scripts[ns][script] = ScriptRecord{
+		Hash:        hash,
+		InstalledAt: time.Now(),
+	}
+}
+
+// MarkUpgraded records that an installed script was upgraded
+func (s *State) MarkUpgraded(ns, script, hash string) {
+	rec, ok := s.Scripts[ns][script]
+	if !ok {
+		return
+	}
+	rec.UpgradedAt = time.Now()
+	if hash != "" {
+		rec.Hash = hash
+	}
+	s.Scripts[ns][script] = rec
+}
+
+// MarkUninstalled forgets that script was installed in namespace ns
+func (s *State) MarkUninstalled(ns, script string) {
+	delete(s.Scripts[ns], script)
+	if len(s.Scripts[ns]) == 0 {
+		delete(s.Scripts, ns)
+	}
+}
+
+// IsInstalled reports whether script is recorded as installed in ns
+func (s *State) IsInstalled(ns, script string) bool {
+	_, ok := s.Scripts[ns][script]
+	return ok
+}
+
+// InstalledScripts returns the names of the scripts installed in ns, sorted
+func (s *State) InstalledScripts(ns string) []string {
+	names := make([]string, 0, len(s.Scripts[ns]))
+	for name := range s.Scripts[ns] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MarkPackageInstalled records that arara installed the package of rec
+// for namespace ns. A package that is already recorded keeps its record.
+func (s *State) MarkPackageInstalled(ns string, rec PackageRecord) {
+	if s.Packages[ns] == nil {
+		s.Packages[ns] = make(map[string]PackageRecord)
+	}
+	if _, ok := s.Packages[ns][rec.Key()]; ok {
+		return
+	}
+	if rec.InstalledAt.IsZero() {
+		rec.InstalledAt = time.Now()
+	}
+	s.Packages[ns][rec.Key()] = rec
+}
+
+// MarkPackageRemoved forgets the package recorded under key in ns
+func (s *State) MarkPackageRemoved(ns, key string) {
+	delete(s.Packages[ns], key)
+	if len(s.Packages[ns]) == 0 {
+		delete(s.Packages, ns)
+	}
+}
+
+// InstalledPackages returns the packages arara installed for ns, sorted
+// by name
+func (s *State) InstalledPackages(ns string) []PackageRecord {
+	recs := make([]PackageRecord, 0, len(s.Packages[ns]))
+	for _, rec := range s.Packages[ns] {
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Name != recs[j].Name {
+			return recs[i].Name < recs[j].Name
+		}
+		return recs[i].Key() < recs[j].Key()
+	})
+	return recs
+}
+
+// PackageNamespaces returns the namespaces the package under key was
+// installed for, sorted
+func (s *State) PackageNamespaces(key string) []string {
+	var namespaces []string
+	for ns, recs := range s.Packages {
+		if _, ok := recs[key]; ok {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// RenameNamespace moves the records of namespace old to new
+func (s *State) RenameNamespace(old, new string) {
+	if recs, ok := s.Scripts[old]; ok {
+		s.Scripts[new] = recs
+		delete(s.Scripts, old)
+	}
+	if recs, ok := s.Packages[old]; ok {
+		s.Packages[new] = recs
+		delete(s.Packages, old)
+	}
+}
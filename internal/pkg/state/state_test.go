@@ -0,0 +1,103 @@
+package state_test
+
+import (
+	"testing"
+
+	"github.com/BuddhiLW/arara/internal/pkg/state"
+)
+
+func TestInstalledScripts(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	st.MarkInstalled("work", "docker", "abc")
+	st.MarkInstalled("work", "neovim", "")
+	st.MarkInstalled("home", "docker", "")
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Reload from disk
+	st, err = state.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if !st.IsInstalled("work", "docker") {
+		t.Error("docker should be installed in work")
+	}
+	if st.IsInstalled("work", "tmux") {
+		t.Error("tmux should not be installed in work")
+	}
+	if got := st.InstalledScripts("work"); len(got) != 2 || got[0] != "docker" || got[1] != "neovim" {
+		t.Errorf("InstalledScripts(work) = %v", got)
+	}
+
+	st.MarkUpgraded("work", "docker", "def")
+	if rec := st.Scripts["work"]["docker"]; rec.Hash != "def" || rec.UpgradedAt.IsZero() {
+		t.Errorf("MarkUpgraded did not update record: %+v", rec)
+	}
+
+	// Upgrading a script that is not installed is a no-op
+	st.MarkUpgraded("work", "tmux", "")
+	if st.IsInstalled("work", "tmux") {
+		t.Error("MarkUpgraded should not install tmux")
+	}
+
+	st.MarkUninstalled("home", "docker")
+	if st.IsInstalled("home", "docker") {
+		t.Error("docker should be uninstalled in home")
+	}
+	if _, ok := st.Scripts["home"]; ok {
+		t.Error("empty namespace should be removed")
+	}
+}
+
+func TestInstalledPackages(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	st.MarkPackageInstalled("work", state.PackageRecord{Name: "fd", Manager: "apt", Package: "fd-find"})
+	st.MarkPackageInstalled("work", state.PackageRecord{Name: "bat", Manager: "cargo", Package: "bat"})
+	st.MarkPackageInstalled("home", state.PackageRecord{Name: "fd", Manager: "apt", Package: "fd-find"})
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Reload from disk
+	st, err = state.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	recs := st.InstalledPackages("work")
+	if len(recs) != 2 || recs[0].Name != "bat" || recs[1].Key() != "apt:fd-find" || recs[1].InstalledAt.IsZero() {
+		t.Errorf("InstalledPackages(work) = %+v", recs)
+	}
+	if got := st.PackageNamespaces("apt:fd-find"); len(got) != 2 || got[0] != "home" || got[1] != "work" {
+		t.Errorf("PackageNamespaces(apt:fd-find) = %v", got)
+	}
+
+	// Recording again keeps the original install time
+	first := recs[1].InstalledAt
+	st.MarkPackageInstalled("work", state.PackageRecord{Name: "fd", Manager: "apt", Package: "fd-find"})
+	if rec := st.Packages["work"]["apt:fd-find"]; !rec.InstalledAt.Equal(first) {
+		t.Errorf("MarkPackageInstalled replaced record: %+v", rec)
+	}
+
+	st.MarkPackageRemoved("home", "apt:fd-find")
+	if _, ok := st.Packages["home"]; ok {
+		t.Error("empty namespace should be removed")
+	}
+	if got := st.PackageNamespaces("apt:fd-find"); len(got) != 1 || got[0] != "work" {
+		t.Errorf("PackageNamespaces(apt:fd-find) = %v", got)
+	}
+}
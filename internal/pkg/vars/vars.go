@@ -4,8 +4,10 @@ const (
 	// Environment variables
 	ActiveNamespaceEnv = "ARARA_ACTIVE_NAMESPACE"
 	DotfilesPathEnv    = "ARARA_DOTFILES_PATH"
+	PrivilegeEnv       = "ARARA_PRIVILEGE"
 
 	// Variable names
 	ActiveNamespaceVar = "active-namespace"
 	DotfilesPathVar    = "dotfiles-path"
+	PrivilegeVar       = "privilege"
 )